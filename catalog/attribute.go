@@ -0,0 +1,180 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+type AttributeType string
+
+const (
+	AttributeString AttributeType = "string"
+	AttributeNumber AttributeType = "number"
+	AttributeBool   AttributeType = "bool"
+	AttributeEnum   AttributeType = "enum"
+)
+
+const maxFacetValues = 20
+
+var (
+	ErrInvalidAttributeDefinition = errors.New("Invalid attribute definition")
+	ErrAttributeTypeChanged       = errors.New("Attribute type cannot change once defined")
+	ErrUnknownAttribute           = errors.New("Unknown attribute")
+	ErrInvalidAttributeValue      = errors.New("Invalid attribute value")
+)
+
+// attributeName keeps attribute names usable as Elasticsearch field names.
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// AttributeDefinition declares an attribute products may carry. Values lists
+// the allowed values of an enum.
+type AttributeDefinition struct {
+	Name   string        `json:"name"`
+	Type   AttributeType `json:"type"`
+	Values []string      `json:"values"`
+}
+
+// Facet counts the products of a search per value of an attribute.
+type Facet struct {
+	Name    string        `json:"name"`
+	Buckets []FacetBucket `json:"buckets"`
+}
+
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+func (d AttributeDefinition) validate() error {
+	if !attributeName.MatchString(d.Name) {
+		return ErrInvalidAttributeDefinition
+	}
+	switch d.Type {
+	case AttributeString, AttributeNumber, AttributeBool:
+		if len(d.Values) > 0 {
+			return ErrInvalidAttributeDefinition
+		}
+	case AttributeEnum:
+		if len(d.Values) == 0 {
+			return ErrInvalidAttributeDefinition
+		}
+	default:
+		return ErrInvalidAttributeDefinition
+	}
+	return nil
+}
+
+// check reports whether v is a valid value of the attribute. Values are the
+// types JSON decodes into.
+func (d AttributeDefinition) check(v interface{}) bool {
+	switch d.Type {
+	case AttributeString:
+		_, ok := v.(string)
+		return ok
+	case AttributeNumber:
+		_, ok := v.(float64)
+		return ok
+	case AttributeBool:
+		_, ok := v.(bool)
+		return ok
+	case AttributeEnum:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		for _, allowed := range d.Values {
+			if s == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *catalogService) attributeDefinitions(ctx context.Context) (map[string]AttributeDefinition, error) {
+	list, err := s.repository.ListAttributeDefinitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := map[string]AttributeDefinition{}
+	for _, d := range list {
+		defs[d.Name] = d
+	}
+	return defs, nil
+}
+
+func (s *catalogService) GetAttributeDefinitions(ctx context.Context) ([]AttributeDefinition, error) {
+	return s.repository.ListAttributeDefinitions(ctx)
+}
+
+// PutAttributeDefinition creates or updates a definition. The type is fixed
+// after creation because it decides how values are indexed, deleting the
+// definition does not free it.
+func (s *catalogService) PutAttributeDefinition(ctx context.Context, d AttributeDefinition) (*AttributeDefinition, error) {
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+
+	old, err := s.repository.GetAttributeDefinition(ctx, d.Name)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	if old != nil && old.Type != d.Type {
+		return nil, ErrAttributeTypeChanged
+	}
+
+	if err := s.repository.PutAttributeDefinition(ctx, d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeleteAttributeDefinition stops products from getting new values for the
+// attribute. Values already stored stay searchable, and the name can only
+// be defined again with the same type.
+func (s *catalogService) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	return s.repository.DeleteAttributeDefinition(ctx, name)
+}
+
+// SetProductAttributes replaces all attribute values of a product.
+func (s *catalogService) SetProductAttributes(ctx context.Context, id string, attributes map[string]interface{}) (*Product, error) {
+	defs, err := s.attributeDefinitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range attributes {
+		d, ok := defs[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownAttribute, name)
+		}
+		if !d.check(v) {
+			return nil, fmt.Errorf("%w for %q", ErrInvalidAttributeValue, name)
+		}
+	}
+
+	if err := s.repository.UpdateProductAttributes(ctx, id, attributes); err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	return s.repository.GetProductByID(ctx, id)
+}
+
+// GetFacets counts the products matching filter per value of each named
+// attribute.
+func (s *catalogService) GetFacets(ctx context.Context, filter ProductFilter, names []string) ([]Facet, error) {
+	defs, err := s.attributeDefinitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if _, ok := defs[name]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownAttribute, name)
+		}
+	}
+
+	return s.repository.FacetCounts(ctx, filter, names, maxFacetValues)
+}
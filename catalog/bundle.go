@@ -0,0 +1,170 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+type ProductKind string
+
+const (
+	KindSimple ProductKind = "simple"
+	KindBundle ProductKind = "bundle"
+)
+
+// BundlePricing decides what a bundle costs.
+type BundlePricing string
+
+const (
+	// PricingFixed charges the bundle's own price.
+	PricingFixed BundlePricing = "fixed"
+	// PricingPercentOff takes Discount percent off the components' total.
+	PricingPercentOff BundlePricing = "percent_off"
+	// PricingAmountOff takes Discount off the components' total.
+	PricingAmountOff BundlePricing = "amount_off"
+)
+
+const maxBundleComponents = 50
+
+var (
+	ErrInvalidBundle          = errors.New("Invalid bundle")
+	ErrBundleComponentMissing = errors.New("Bundle component not found")
+)
+
+// Bundle makes a product a fixed set of other products sold together.
+type Bundle struct {
+	Components []BundleComponent `json:"components"`
+	Pricing    BundlePricing     `json:"pricing"`
+	Discount   float64           `json:"discount"`
+}
+
+type BundleComponent struct {
+	ProductID string `json:"product_id"`
+	Quantity  uint64 `json:"quantity"`
+}
+
+// Kind reports whether the product is sold on its own or as a bundle.
+func (p Product) Kind() ProductKind {
+	if p.Bundle != nil {
+		return KindBundle
+	}
+	return KindSimple
+}
+
+func (b Bundle) validate(id string) error {
+	if len(b.Components) == 0 || len(b.Components) > maxBundleComponents {
+		return ErrInvalidBundle
+	}
+	seen := map[string]bool{}
+	for _, c := range b.Components {
+		if c.ProductID == "" || c.ProductID == id || c.Quantity == 0 || seen[c.ProductID] {
+			return ErrInvalidBundle
+		}
+		seen[c.ProductID] = true
+	}
+
+	switch b.Pricing {
+	case PricingFixed:
+		if b.Discount != 0 {
+			return ErrInvalidBundle
+		}
+	case PricingPercentOff:
+		if b.Discount < 0 || b.Discount > 100 {
+			return ErrInvalidBundle
+		}
+	case PricingAmountOff:
+		if b.Discount < 0 {
+			return ErrInvalidBundle
+		}
+	default:
+		return ErrInvalidBundle
+	}
+	return nil
+}
+
+// Price returns what one bundle costs given its own price and the unit
+// price of every component, rounded to cents.
+func (b Bundle) Price(own float64, prices map[string]float64) (float64, error) {
+	if b.Pricing == PricingFixed {
+		return own, nil
+	}
+
+	total := 0.0
+	for _, c := range b.Components {
+		price, ok := prices[c.ProductID]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrBundleComponentMissing, c.ProductID)
+		}
+		total += price * float64(c.Quantity)
+	}
+
+	switch b.Pricing {
+	case PricingPercentOff:
+		total *= 1 - b.Discount/100
+	case PricingAmountOff:
+		total = math.Max(total-b.Discount, 0)
+	}
+	return math.Round(total*100) / 100, nil
+}
+
+// SetProductBundle turns a product into a bundle of existing products, or
+// back into a simple product when bundle is nil. Bundles cannot contain
+// other bundles. Unless the pricing is fixed, the product's price is set
+// from the current component prices; orders price bundles again when
+// they are placed.
+func (s *catalogService) SetProductBundle(ctx context.Context, id string, bundle *Bundle) (*Product, error) {
+	p, err := s.repository.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	price := p.Price
+	if bundle != nil {
+		if err := bundle.validate(id); err != nil {
+			return nil, err
+		}
+		containing, err := s.repository.FilterProducts(ctx, ProductFilter{
+			Equals: &FieldEquals{Field: "bundle.components.product_id", Value: id},
+		}, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(containing) > 0 {
+			return nil, fmt.Errorf("%w: %s is part of bundle %s", ErrInvalidBundle, id, containing[0].Product.ID)
+		}
+
+		ids := []string{}
+		for _, c := range bundle.Components {
+			ids = append(ids, c.ProductID)
+		}
+		components, err := s.repository.ListProductWithIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		prices := map[string]float64{}
+		for _, c := range components {
+			if c.Bundle != nil {
+				return nil, fmt.Errorf("%w: %s is itself a bundle", ErrInvalidBundle, c.ID)
+			}
+			prices[c.ID] = c.Price
+		}
+		for _, c := range bundle.Components {
+			if _, ok := prices[c.ProductID]; !ok {
+				return nil, fmt.Errorf("%w: %s", ErrBundleComponentMissing, c.ProductID)
+			}
+		}
+
+		if price, err = bundle.Price(p.Price, prices); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repository.UpdateProductBundle(ctx, id, bundle, price); err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	return s.repository.GetProductByID(ctx, id)
+}
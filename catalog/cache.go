@@ -0,0 +1,280 @@
+package catalog
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+// cachedRepository is a read-through cache in front of product lookups by
+// id. Entries expire after ttl, the least recently used entry is evicted once
+// size is reached and writes through PutProduct invalidate the product.
+type cachedRepository struct {
+	Repository
+	size   int
+	ttl    time.Duration
+	group  singleflight.Group
+	hits   uint64
+	misses uint64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	// generation is bumped on every invalidation so that lookups which were
+	// already in flight do not put stale products back into the cache.
+	generation uint64
+}
+
+type cacheEntry struct {
+	product   Product
+	expiresAt time.Time
+}
+
+func NewCachedRepository(r Repository, size int, ttl time.Duration) *cachedRepository {
+	return &cachedRepository{
+		Repository: r,
+		size:       size,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (r *cachedRepository) Stats() CacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&r.hits),
+		Misses: atomic.LoadUint64(&r.misses),
+		Size:   r.order.Len(),
+	}
+}
+
+func (r *cachedRepository) PutProduct(ctx context.Context, p Product) error {
+	err := r.Repository.PutProduct(ctx, p)
+	r.invalidate(p.ID)
+	return err
+}
+
+func (r *cachedRepository) DeleteProduct(ctx context.Context, id string) error {
+	err := r.Repository.DeleteProduct(ctx, id)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductRating(ctx context.Context, id string, update func(rating Rating) (Rating, error)) error {
+	err := r.Repository.UpdateProductRating(ctx, id, update)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductStatus(ctx context.Context, id string, update func(status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (ProductStatus, *time.Time, *time.Time, error)) error {
+	err := r.Repository.UpdateProductStatus(ctx, id, update)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductAttributes(ctx context.Context, id string, attributes map[string]interface{}) error {
+	err := r.Repository.UpdateProductAttributes(ctx, id, attributes)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductTranslations(ctx context.Context, id string, translations map[string]Translation) error {
+	err := r.Repository.UpdateProductTranslations(ctx, id, translations)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductSlug(ctx context.Context, id string, slug string) error {
+	err := r.Repository.UpdateProductSlug(ctx, id, slug)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductBundle(ctx context.Context, id string, bundle *Bundle, price float64) error {
+	err := r.Repository.UpdateProductBundle(ctx, id, bundle, price)
+	r.invalidate(id)
+	return err
+}
+
+func (r *cachedRepository) UpdateProductImages(ctx context.Context, id string, update func(images []Image) ([]Image, error)) (*Product, error) {
+	p, err := r.Repository.UpdateProductImages(ctx, id, update)
+	r.invalidate(id)
+	return p, err
+}
+
+func (r *cachedRepository) GetProductByID(ctx context.Context, id string) (*Product, error) {
+	if p, ok := r.get(id); ok {
+		atomic.AddUint64(&r.hits, 1)
+		return &p, nil
+	}
+	atomic.AddUint64(&r.misses, 1)
+
+	// The lookup is shared by every caller waiting for id, the first one
+	// giving up must not fail the others. Callers that come after an
+	// invalidation start a lookup of their own instead of joining one that
+	// may return the old product.
+	loadCtx := context.WithoutCancel(ctx)
+	gen := r.currentGeneration()
+	v, err, _ := r.group.Do(fmt.Sprintf("%s@%d", id, gen), func() (interface{}, error) {
+		p, err := r.Repository.GetProductByID(loadCtx, id)
+		if err != nil {
+			return nil, err
+		}
+		r.set(*p, gen)
+		return *p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := copyProduct(v.(Product))
+	return &p, nil
+}
+
+func (r *cachedRepository) ListProductWithIDs(ctx context.Context, ids []string) ([]Product, error) {
+	found := make(map[string]Product, len(ids))
+	missing := []string{}
+	for _, id := range ids {
+		if p, ok := r.get(id); ok {
+			found[id] = p
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	atomic.AddUint64(&r.hits, uint64(len(ids)-len(missing)))
+	atomic.AddUint64(&r.misses, uint64(len(missing)))
+
+	if len(missing) > 0 {
+		loadCtx := context.WithoutCancel(ctx)
+		gen := r.currentGeneration()
+		v, err, _ := r.group.Do(fmt.Sprintf("ids:%s@%d", strings.Join(missing, ","), gen), func() (interface{}, error) {
+			products, err := r.Repository.ListProductWithIDs(loadCtx, missing)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range products {
+				r.set(p, gen)
+			}
+			return products, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range v.([]Product) {
+			found[p.ID] = copyProduct(p)
+		}
+	}
+
+	// Keep the order of the request, dropping ids that were not found.
+	products := []Product{}
+	for _, id := range ids {
+		if p, ok := found[id]; ok {
+			products = append(products, p)
+		}
+	}
+
+	return products, nil
+}
+
+func (r *cachedRepository) get(id string) (Product, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[id]
+	if !ok {
+		return Product{}, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		r.order.Remove(el)
+		delete(r.entries, id)
+		return Product{}, false
+	}
+
+	r.order.MoveToFront(el)
+	return copyProduct(entry.product), true
+}
+
+func (r *cachedRepository) currentGeneration() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.generation
+}
+
+func (r *cachedRepository) set(p Product, gen uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if gen != r.generation {
+		return
+	}
+
+	entry := &cacheEntry{product: copyProduct(p), expiresAt: time.Now().Add(r.ttl)}
+	if el, ok := r.entries[p.ID]; ok {
+		el.Value = entry
+		r.order.MoveToFront(el)
+		return
+	}
+
+	r.entries[p.ID] = r.order.PushFront(entry)
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).product.ID)
+	}
+}
+
+func (r *cachedRepository) invalidate(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	if el, ok := r.entries[id]; ok {
+		r.order.Remove(el)
+		delete(r.entries, id)
+	}
+}
+
+// copyProduct copies p down to its slices and maps, so that callers
+// changing a product they got do not change what is cached.
+func copyProduct(p Product) Product {
+	p.Images = slices.Clone(p.Images)
+	for i := range p.Images {
+		p.Images[i].Thumbnails = slices.Clone(p.Images[i].Thumbnails)
+	}
+	if p.PublishAt != nil {
+		t := *p.PublishAt
+		p.PublishAt = &t
+	}
+	if p.UnpublishAt != nil {
+		t := *p.UnpublishAt
+		p.UnpublishAt = &t
+	}
+	p.Attributes = maps.Clone(p.Attributes)
+	p.Translations = maps.Clone(p.Translations)
+	if p.Bundle != nil {
+		bundle := *p.Bundle
+		bundle.Components = slices.Clone(bundle.Components)
+		p.Bundle = &bundle
+	}
+	return p
+}
@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// productRepository serves products from memory and counts lookups. When
+// block is set, lookups wait on it after reading the product.
+type productRepository struct {
+	Repository
+
+	mu       sync.Mutex
+	products map[string]Product
+	lookups  int
+	block    chan struct{}
+	started  chan struct{}
+}
+
+func (r *productRepository) GetProductByID(ctx context.Context, id string) (*Product, error) {
+	r.mu.Lock()
+	r.lookups++
+	p, ok := r.products[id]
+	block, started := r.block, r.started
+	r.mu.Unlock()
+
+	if block != nil {
+		started <- struct{}{}
+		<-block
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *productRepository) PutProduct(ctx context.Context, p Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products[p.ID] = p
+	return nil
+}
+
+func (r *productRepository) lookupCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lookups
+}
+
+func newProductRepository(names ...string) *productRepository {
+	r := &productRepository{products: map[string]Product{}}
+	for _, name := range names {
+		r.products[name] = Product{ID: name, Name: name}
+	}
+	return r
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	r := newProductRepository("a", "b", "c")
+	cache := NewCachedRepository(r, 2, time.Minute)
+
+	for _, id := range []string{"a", "b", "a", "c"} {
+		if _, err := cache.GetProductByID(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.lookupCount() != 3 {
+		t.Fatalf("lookups = %d, want 3", r.lookupCount())
+	}
+
+	// b was used least recently when c came in.
+	if _, ok := cache.get("b"); ok {
+		t.Error("b is still cached")
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := cache.get(id); !ok {
+			t.Errorf("%s is not cached", id)
+		}
+	}
+	if stats := cache.Stats(); stats.Size != 2 || stats.Hits != 1 || stats.Misses != 3 {
+		t.Errorf("stats = %+v, want 2 entries, 1 hit and 3 misses", stats)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	r := newProductRepository("a")
+	cache := NewCachedRepository(r, 10, time.Millisecond)
+
+	if _, err := cache.GetProductByID(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.GetProductByID(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.lookupCount() != 2 {
+		t.Errorf("lookups = %d, want 2", r.lookupCount())
+	}
+}
+
+func TestCacheInvalidatedDuringLookup(t *testing.T) {
+	ctx := context.Background()
+	r := newProductRepository("a")
+	r.block = make(chan struct{})
+	r.started = make(chan struct{}, 2)
+	cache := NewCachedRepository(r, 10, time.Minute)
+
+	// The first lookup reads the old product and waits.
+	stale := make(chan *Product)
+	go func() {
+		p, err := cache.GetProductByID(ctx, "a")
+		if err != nil {
+			t.Error(err)
+		}
+		stale <- p
+	}()
+	<-r.started
+
+	if err := cache.PutProduct(ctx, Product{ID: "a", Name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lookup after the write must not join the one in flight.
+	fresh := make(chan *Product)
+	go func() {
+		p, err := cache.GetProductByID(ctx, "a")
+		if err != nil {
+			t.Error(err)
+		}
+		fresh <- p
+	}()
+	<-r.started
+	close(r.block)
+
+	if p := <-stale; p == nil || p.Name != "a" {
+		t.Errorf("first lookup = %+v, want the old product", p)
+	}
+	if p := <-fresh; p == nil || p.Name != "new" {
+		t.Errorf("second lookup = %+v, want the new product", p)
+	}
+
+	p, ok := cache.get("a")
+	if !ok || p.Name != "new" {
+		t.Errorf("cached = %+v, %v, want the new product", p, ok)
+	}
+}
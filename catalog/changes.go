@@ -0,0 +1,194 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+var ErrInvalidToken = errors.New("Invalid watch token")
+
+const (
+	watchBatchSize    = 100
+	watchPollInterval = time.Second
+	// changeWriteTimeout is how long a product write may take after it
+	// logged its change. Watchers skip changes that have not landed by then.
+	changeWriteTimeout = 30 * time.Second
+)
+
+// ProductChange is one entry of the catalog change log. Product is nil for
+// deletions.
+type ProductChange struct {
+	Sequence  uint64     `json:"sequence"`
+	Type      ChangeType `json:"type"`
+	ProductID string     `json:"product_id"`
+	Product   *Product   `json:"product"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Token is the value a watcher passes back to resume right after this change.
+func (c ProductChange) Token() string {
+	return strconv.FormatUint(c.Sequence, 10)
+}
+
+// changeState is how far a logged change got into the products.
+type changeState int
+
+const (
+	changeLanded changeState = iota
+	// changeSkipped changes never landed or were overwritten since.
+	changeSkipped
+	// changePending changes may still land.
+	changePending
+)
+
+// state compares c with the change sequence each existing product carries
+// now. later are the changes logged after c in the same batch.
+func (c ProductChange) state(sequences map[string]uint64, later []ProductChange, now time.Time) changeState {
+	sequence, exists := sequences[c.ProductID]
+	switch {
+	case exists && sequence == 0:
+		// The product was last written before it carried its sequence.
+		return changeLanded
+	case exists && sequence == c.Sequence, !exists && c.Type == ChangeDeleted:
+		return changeLanded
+	case exists && sequence > c.Sequence:
+		return changeSkipped
+	}
+
+	// A product is only deleted after its last write landed.
+	for _, l := range later {
+		if l.ProductID == c.ProductID && l.Type == ChangeDeleted {
+			return changeSkipped
+		}
+	}
+	if now.Sub(c.CreatedAt) > changeWriteTimeout {
+		return changeSkipped
+	}
+	return changePending
+}
+
+// changeNotifier wakes up watchers as soon as this instance writes a change
+// instead of waiting for the next poll.
+type changeNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{ch: make(chan struct{})}
+}
+
+func (n *changeNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.ch
+}
+
+func (n *changeNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// WatchProducts calls send for every change after token until ctx is done or
+// send fails. An empty token starts at the current end of the log, "0" at its
+// beginning. Only the last snapshot of a product is sent, changes that were
+// overwritten by the time the watcher gets to them are skipped.
+func (s *catalogService) WatchProducts(ctx context.Context, token string, send func(ProductChange) error) error {
+	var after uint64
+	var err error
+	if token == "" {
+		after, err = s.repository.LastChangeSequence(ctx)
+	} else {
+		after, err = strconv.ParseUint(token, 10, 64)
+		if err != nil {
+			err = ErrInvalidToken
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	poll := time.NewTicker(watchPollInterval)
+	defer poll.Stop()
+
+	for {
+		wake := s.changes.wait()
+
+		changes, err := s.repository.ListChanges(ctx, after, watchBatchSize)
+		if err != nil {
+			return err
+		}
+
+		// Read after the changes, so every one of them that landed shows.
+		ids := []string{}
+		for _, c := range changes {
+			ids = append(ids, c.ProductID)
+		}
+		sequences, err := s.repository.ProductChangeSequences(ctx, ids)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		for i, c := range changes {
+			if c.Sequence != after+1 {
+				// A change is only written once the one before it exists,
+				// so when that one exists too it is just not searchable
+				// yet. Logs written before that rule can have real holes,
+				// only those are skipped.
+				exists, err := s.repository.HasChange(ctx, c.Sequence-1)
+				if err != nil {
+					return err
+				}
+				if exists {
+					break
+				}
+			}
+			state := c.state(sequences, changes[i+1:], now)
+			if state == changePending {
+				break
+			}
+			if state == changeLanded {
+				if err := send(c); err != nil {
+					return err
+				}
+			}
+			after = c.Sequence
+		}
+
+		if len(changes) == watchBatchSize && changes[len(changes)-1].Sequence == after {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		case <-poll.C:
+		}
+	}
+}
+
+func (s *catalogService) DeleteProduct(ctx context.Context, id string) error {
+	if err := s.repository.DeleteProduct(ctx, id); err != nil {
+		return err
+	}
+
+	s.changes.notify()
+	return nil
+}
@@ -0,0 +1,90 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProductChangeState(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Second)
+	old := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		change    ProductChange
+		sequences map[string]uint64
+		later     []ProductChange
+		want      changeState
+	}{
+		{
+			name:      "current snapshot",
+			change:    ProductChange{Sequence: 5, Type: ChangeUpdated, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{"a": 5},
+			want:      changeLanded,
+		},
+		{
+			name:      "product written before it carried a sequence",
+			change:    ProductChange{Sequence: 5, Type: ChangeUpdated, ProductID: "a", CreatedAt: old},
+			sequences: map[string]uint64{"a": 0},
+			want:      changeLanded,
+		},
+		{
+			name:      "overwritten since",
+			change:    ProductChange{Sequence: 5, Type: ChangeUpdated, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{"a": 7},
+			want:      changeSkipped,
+		},
+		{
+			name:      "write in flight",
+			change:    ProductChange{Sequence: 5, Type: ChangeUpdated, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{"a": 3},
+			want:      changePending,
+		},
+		{
+			name:      "write that never landed",
+			change:    ProductChange{Sequence: 5, Type: ChangeUpdated, ProductID: "a", CreatedAt: old},
+			sequences: map[string]uint64{"a": 3},
+			want:      changeSkipped,
+		},
+		{
+			name:      "create in flight",
+			change:    ProductChange{Sequence: 5, Type: ChangeCreated, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{},
+			want:      changePending,
+		},
+		{
+			name:      "created then deleted",
+			change:    ProductChange{Sequence: 5, Type: ChangeCreated, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{},
+			later:     []ProductChange{{Sequence: 6, Type: ChangeDeleted, ProductID: "a", CreatedAt: recent}},
+			want:      changeSkipped,
+		},
+		{
+			name:      "deleted",
+			change:    ProductChange{Sequence: 5, Type: ChangeDeleted, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{},
+			want:      changeLanded,
+		},
+		{
+			name:      "delete in flight",
+			change:    ProductChange{Sequence: 5, Type: ChangeDeleted, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{"a": 4},
+			want:      changePending,
+		},
+		{
+			name:      "deleted then created again",
+			change:    ProductChange{Sequence: 5, Type: ChangeDeleted, ProductID: "a", CreatedAt: recent},
+			sequences: map[string]uint64{"a": 6},
+			want:      changeSkipped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.state(tt.sequences, tt.later, now); got != tt.want {
+				t.Errorf("state = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
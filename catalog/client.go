@@ -2,9 +2,13 @@ package catalog
 
 import (
 	"context"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/timothydzokoto/grpc_graphql_microservice/catalog/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 type Client struct {
@@ -37,25 +41,599 @@ func (c *Client) PostProduct(ctx context.Context, name string, description strin
 		return nil, err
 	}
 
-	return &Product{ID: r.Product.Id, Name: r.Product.Name, Description: r.Product.Description, Price: r.Product.Price}, nil
+	return productFromProto(r.Product), nil
 }
 
 func (c *Client) GetProduct(ctx context.Context, id string) (*Product, error) {
-	r, err := c.service.GetProduct(ctx, &pb.GetProductRequest{Id: id})
+	r, err := c.service.GetProduct(ctx, &pb.GetProductRequest{Id: id, Locale: localeFromContext(ctx)})
 	if err != nil {
 		return nil, err
 	}
 
-	return &Product{ID: r.Product.Id, Name: r.Product.Name, Description: r.Product.Description, Price: r.Product.Price}, nil
+	return productFromProto(r.Product), nil
 }
+
+// GetProductBySlug also reports whether slug is an earlier slug of the
+// product, in which case callers should redirect to its current one.
+func (c *Client) GetProductBySlug(ctx context.Context, slug string) (*Product, bool, error) {
+	r, err := c.service.GetProductBySlug(ctx, &pb.GetProductBySlugRequest{Slug: slug, Locale: localeFromContext(ctx)})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return productFromProto(r.Product), r.Moved, nil
+}
+
+func (c *Client) ChangeProductSlug(ctx context.Context, id string, slug string) (*Product, error) {
+	r, err := c.service.ChangeProductSlug(ctx, &pb.ChangeProductSlugRequest{Id: id, Slug: slug})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
 func (c *Client) GetProducts(ctx context.Context, skip uint64, take uint64, query string, ids []string) ([]Product, error) {
-	r, err := c.service.GetProducts(ctx, &pb.GetProductsRequest{Skip: skip, Take: take, Query: query, Ids: ids})
+	r, err := c.service.GetProducts(ctx, &pb.GetProductsRequest{Skip: skip, Take: take, Query: query, Ids: ids, Locale: localeFromContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	products := []Product{}
+	for _, p := range r.Products {
+		products = append(products, *productFromProto(p))
+	}
+	return products, nil
+}
+
+// GetAllProducts lists products like GetProducts but includes drafts and
+// archived products.
+func (c *Client) GetAllProducts(ctx context.Context, skip uint64, take uint64, query string, ids []string) ([]Product, error) {
+	r, err := c.service.GetProducts(ctx, &pb.GetProductsRequest{Skip: skip, Take: take, Query: query, Ids: ids, IncludeUnpublished: true, Locale: localeFromContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+
+	products := []Product{}
+	for _, p := range r.Products {
+		products = append(products, *productFromProto(p))
+	}
+	return products, nil
+}
+
+func (c *Client) SetProductStatus(ctx context.Context, id string, status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (*Product, error) {
+	r, err := c.service.SetProductStatus(ctx, &pb.SetProductStatusRequest{
+		Id:          id,
+		Status:      string(status),
+		PublishAt:   optionalTimeToProto(publishAt),
+		UnpublishAt: optionalTimeToProto(unpublishAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
+// SearchProducts also returns a corrected query when nothing matched and
+// the query looks misspelled.
+func (c *Client) SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]SearchHit, string, error) {
+	r, err := c.service.GetProducts(ctx, &pb.GetProductsRequest{Skip: skip, Take: take, Query: query, Locale: localeFromContext(ctx)})
+	if err != nil {
+		return nil, "", err
+	}
+	return hitsFromProto(r.Hits), r.Suggestion, nil
+}
+
+func (c *Client) FilterProducts(ctx context.Context, filter ProductFilter, skip uint64, take uint64) ([]SearchHit, error) {
+	hits, _, err := c.FilterProductsWithFacets(ctx, filter, nil, skip, take)
+	return hits, err
+}
+
+// FilterProductsWithFacets also counts the matching products per value of
+// each named attribute.
+func (c *Client) FilterProductsWithFacets(ctx context.Context, filter ProductFilter, facets []string, skip uint64, take uint64) ([]SearchHit, []Facet, error) {
+	r, err := c.service.GetProducts(ctx, &pb.GetProductsRequest{Skip: skip, Take: take, Filter: filterToProto(filter), Facets: facets, Locale: localeFromContext(ctx)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := []Facet{}
+	for _, f := range r.Facets {
+		facet := Facet{Name: f.Name, Buckets: []FacetBucket{}}
+		for _, b := range f.Buckets {
+			facet.Buckets = append(facet.Buckets, FacetBucket{Value: b.Value, Count: b.Count})
+		}
+		res = append(res, facet)
+	}
+	return hitsFromProto(r.Hits), res, nil
+}
+
+func (c *Client) GetAttributeDefinitions(ctx context.Context) ([]AttributeDefinition, error) {
+	r, err := c.service.GetAttributeDefinitions(ctx, &pb.GetAttributeDefinitionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []AttributeDefinition{}
+	for _, d := range r.Definitions {
+		definitions = append(definitions, definitionFromProto(d))
+	}
+	return definitions, nil
+}
+
+func (c *Client) PutAttributeDefinition(ctx context.Context, d AttributeDefinition) (*AttributeDefinition, error) {
+	r, err := c.service.PutAttributeDefinition(ctx, &pb.PutAttributeDefinitionRequest{Definition: definitionToProto(d)})
+	if err != nil {
+		return nil, err
+	}
+
+	definition := definitionFromProto(r.Definition)
+	return &definition, nil
+}
+
+func (c *Client) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	_, err := c.service.DeleteAttributeDefinition(ctx, &pb.DeleteAttributeDefinitionRequest{Name: name})
+	return err
+}
+
+func (c *Client) SetProductTranslations(ctx context.Context, id string, translations map[string]Translation) (*Product, error) {
+	r, err := c.service.SetProductTranslations(ctx, &pb.SetProductTranslationsRequest{Id: id, Translations: translationsToProto(translations)})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
+// SetProductBundle makes the product a bundle, or a simple product again
+// when bundle is nil.
+func (c *Client) SetProductBundle(ctx context.Context, id string, bundle *Bundle) (*Product, error) {
+	r, err := c.service.SetProductBundle(ctx, &pb.SetProductBundleRequest{Id: id, Bundle: bundleToProto(bundle)})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
+func (c *Client) SetProductAttributes(ctx context.Context, id string, attributes map[string]interface{}) (*Product, error) {
+	r, err := c.service.SetProductAttributes(ctx, &pb.SetProductAttributesRequest{Id: id, Attributes: attributesToProto(attributes)})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
+func (c *Client) GetSynonyms(ctx context.Context) ([]Synonym, error) {
+	r, err := c.service.GetSynonyms(ctx, &pb.GetSynonymsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	synonyms := []Synonym{}
+	for _, s := range r.Synonyms {
+		synonyms = append(synonyms, Synonym{ID: s.Id, Terms: s.Terms})
+	}
+	return synonyms, nil
+}
+
+func (c *Client) PutSynonym(ctx context.Context, id string, terms []string) (*Synonym, error) {
+	r, err := c.service.PutSynonym(ctx, &pb.PutSynonymRequest{Id: id, Terms: terms})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Synonym{ID: r.Synonym.Id, Terms: r.Synonym.Terms}, nil
+}
+
+func (c *Client) DeleteSynonym(ctx context.Context, id string) error {
+	_, err := c.service.DeleteSynonym(ctx, &pb.DeleteSynonymRequest{Id: id})
+	return err
+}
+
+func (c *Client) GetRelatedProducts(ctx context.Context, id string, limit uint64) ([]Product, error) {
+	r, err := c.service.GetRelatedProducts(ctx, &pb.GetRelatedProductsRequest{Id: id, Limit: limit})
 	if err != nil {
 		return nil, err
 	}
+
 	products := []Product{}
 	for _, p := range r.Products {
-		products = append(products, Product{ID: p.Id, Name: p.Name, Description: p.Description, Price: p.Price})
+		products = append(products, *productFromProto(p))
 	}
 	return products, nil
 }
+
+func (c *Client) DeleteProduct(ctx context.Context, id string) error {
+	_, err := c.service.DeleteProduct(ctx, &pb.DeleteProductRequest{Id: id})
+	return err
+}
+
+// WatchProducts calls handle for every product change after token until ctx
+// is cancelled, the stream breaks or handle returns an error. Callers resume
+// by passing the Token of the last change they handled.
+func (c *Client) WatchProducts(ctx context.Context, token string, handle func(ProductChange) error) error {
+	stream, err := c.service.WatchProducts(ctx, &pb.WatchProductsRequest{Token: token})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		change := ProductChange{
+			ProductID: event.ProductId,
+		}
+		for t, pt := range changeTypes {
+			if pt == event.Type {
+				change.Type = t
+			}
+		}
+		if event.Product != nil {
+			change.Product = productFromProto(event.Product)
+		}
+		if err := change.CreatedAt.UnmarshalBinary(event.CreatedAt); err != nil {
+			return err
+		}
+		if change.Sequence, err = strconv.ParseUint(event.Token, 10, 64); err != nil {
+			return ErrInvalidToken
+		}
+
+		if err := handle(change); err != nil {
+			return err
+		}
+	}
+}
+
+// ExportProducts calls handle for every product, in every status, ordered by
+// id and starting after the given id.
+func (c *Client) ExportProducts(ctx context.Context, after string, handle func(Product) error) error {
+	stream, err := c.service.ExportProducts(ctx, &pb.ExportProductsRequest{After: after})
+	if err != nil {
+		return err
+	}
+
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := handle(*productFromProto(p)); err != nil {
+			return err
+		}
+	}
+}
+
+// SlugOwner returns the id of the product that has or had slug, whatever
+// its status, or an empty id when there is none.
+func (c *Client) SlugOwner(ctx context.Context, slug string) (string, error) {
+	r, err := c.service.GetProductBySlug(ctx, &pb.GetProductBySlugRequest{Slug: slug, IncludeUnpublished: true})
+	if status.Convert(err).Message() == ErrNotFound.Error() {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return r.Product.Id, nil
+}
+
+func (c *Client) ImportProducts(ctx context.Context, products []Product) error {
+	req := &pb.ImportProductsRequest{}
+	for _, p := range products {
+		req.Products = append(req.Products, productToProto(&p))
+	}
+
+	_, err := c.service.ImportProducts(ctx, req)
+	return err
+}
+
+func (c *Client) UploadProductImage(ctx context.Context, productID string, altText string, position int, r io.Reader) (*Image, error) {
+	stream, err := c.service.UploadProductImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.UploadProductImageRequest{
+		Data: &pb.UploadProductImageRequest_Metadata_{Metadata: &pb.UploadProductImageRequest_Metadata{
+			ProductId: productID,
+			AltText:   altText,
+			Position:  int32(position),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := &pb.UploadProductImageRequest{
+				Data: &pb.UploadProductImageRequest_Chunk{Chunk: append([]byte(nil), buf[:n]...)},
+			}
+			if err := stream.Send(chunk); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+
+	image := imageFromProto(res.Image)
+	return &image, nil
+}
+
+// UpdateProductImage changes the alt text and position of an image, nil
+// leaves them as they are.
+func (c *Client) UpdateProductImage(ctx context.Context, productID string, imageID string, altText *string, position *int) (*Product, error) {
+	var pos *int32
+	if position != nil {
+		p := int32(*position)
+		pos = &p
+	}
+
+	r, err := c.service.UpdateProductImage(ctx, &pb.UpdateProductImageRequest{
+		ProductId: productID,
+		ImageId:   imageID,
+		AltText:   altText,
+		Position:  pos,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
+func (c *Client) DeleteProductImage(ctx context.Context, productID string, imageID string) (*Product, error) {
+	r, err := c.service.DeleteProductImage(ctx, &pb.DeleteProductImageRequest{
+		ProductId: productID,
+		ImageId:   imageID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return productFromProto(r.Product), nil
+}
+
+func (c *Client) PostReview(ctx context.Context, productID string, accountID string, rating int, body string) (*Review, error) {
+	r, err := c.service.PostReview(ctx, &pb.PostReviewRequest{
+		ProductId: productID,
+		AccountId: accountID,
+		Rating:    int32(rating),
+		Body:      body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewFromProto(r.Review)
+}
+
+func (c *Client) GetReviews(ctx context.Context, productID string, skip uint64, take uint64) ([]Review, uint64, error) {
+	r, err := c.service.GetReviews(ctx, &pb.GetReviewsRequest{
+		ProductId: productID,
+		Skip:      skip,
+		Take:      take,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reviews := []Review{}
+	for _, rp := range r.Reviews {
+		review, err := reviewFromProto(rp)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, *review)
+	}
+
+	return reviews, r.Total, nil
+}
+
+func productFromProto(p *pb.Product) *Product {
+	images := []Image{}
+	for _, i := range p.Images {
+		images = append(images, imageFromProto(i))
+	}
+
+	product := &Product{
+		ID:          p.Id,
+		Slug:        p.Slug,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Images:      images,
+		Rating: Rating{
+			Average: p.GetRating().GetAverage(),
+			Count:   p.GetRating().GetCount(),
+		},
+		Status:       ProductStatus(p.Status),
+		Attributes:   attributesFromProto(p.Attributes),
+		Translations: translationsFromProto(p.Translations),
+		Bundle:       bundleFromProto(p.Bundle),
+	}
+	// A product that fails to decode its schedule is still worth showing.
+	product.PublishAt, _ = optionalTimeFromProto(p.PublishAt)
+	product.UnpublishAt, _ = optionalTimeFromProto(p.UnpublishAt)
+
+	return product
+}
+
+func bundleFromProto(b *pb.Bundle) *Bundle {
+	if b == nil {
+		return nil
+	}
+
+	components := []BundleComponent{}
+	for _, c := range b.Components {
+		components = append(components, BundleComponent{ProductID: c.ProductId, Quantity: c.Quantity})
+	}
+	return &Bundle{Components: components, Pricing: BundlePricing(b.Pricing), Discount: b.Discount}
+}
+
+// attributesFromProto returns nil for no attributes, matching what the
+// repository reads back for a product without any.
+func attributesFromProto(values map[string]*pb.AttributeValue) map[string]interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	attributes := map[string]interface{}{}
+	for name, v := range values {
+		switch v := v.Value.(type) {
+		case *pb.AttributeValue_StringValue:
+			attributes[name] = v.StringValue
+		case *pb.AttributeValue_NumberValue:
+			attributes[name] = v.NumberValue
+		case *pb.AttributeValue_BoolValue:
+			attributes[name] = v.BoolValue
+		}
+	}
+	return attributes
+}
+
+func translationsFromProto(translations map[string]*pb.Translation) map[string]Translation {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	res := map[string]Translation{}
+	for locale, t := range translations {
+		res[locale] = Translation{Name: t.Name, Description: t.Description}
+	}
+	return res
+}
+
+func definitionFromProto(d *pb.AttributeDefinition) AttributeDefinition {
+	if d == nil {
+		return AttributeDefinition{}
+	}
+	return AttributeDefinition{Name: d.Name, Type: AttributeType(d.Type), Values: d.Values}
+}
+
+func optionalTimeFromProto(b []byte) (*time.Time, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	t := time.Time{}
+	if err := t.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func imageFromProto(i *pb.ProductImage) Image {
+	thumbnails := []Thumbnail{}
+	for _, t := range i.Thumbnails {
+		thumbnails = append(thumbnails, Thumbnail{
+			Size:   t.Size,
+			URL:    t.Url,
+			Width:  int(t.Width),
+			Height: int(t.Height),
+		})
+	}
+
+	return Image{
+		ID:          i.Id,
+		URL:         i.Url,
+		AltText:     i.AltText,
+		Position:    int(i.Position),
+		ContentType: i.ContentType,
+		Width:       int(i.Width),
+		Height:      int(i.Height),
+		Thumbnails:  thumbnails,
+	}
+}
+
+func reviewFromProto(r *pb.Review) (*Review, error) {
+	review := &Review{
+		ID:        r.Id,
+		ProductID: r.ProductId,
+		AccountID: r.AccountId,
+		Rating:    int(r.Rating),
+		Body:      r.Body,
+	}
+	if err := review.CreatedAt.UnmarshalBinary(r.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func hitsFromProto(hs []*pb.SearchHit) []SearchHit {
+	hits := []SearchHit{}
+	for _, h := range hs {
+		highlights := map[string][]string{}
+		for _, hl := range h.Highlights {
+			highlights[hl.Field] = hl.Fragments
+		}
+		hits = append(hits, SearchHit{
+			Product:    *productFromProto(h.Product),
+			Score:      h.Score,
+			Highlights: highlights,
+		})
+	}
+	return hits
+}
+
+func filterToProto(f ProductFilter) *pb.ProductFilter {
+	switch {
+	case f.And != nil:
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_And{And: &pb.ProductFilter_Group{Filters: filtersToProto(f.And)}}}
+	case f.Or != nil:
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Or{Or: &pb.ProductFilter_Group{Filters: filtersToProto(f.Or)}}}
+	case f.IDs != nil:
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Ids{Ids: &pb.ProductFilter_IDs{Ids: f.IDs}}}
+	case f.Query != "":
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Query{Query: f.Query}}
+	case f.Price != nil:
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Price{Price: &pb.ProductFilter_PriceRange{Min: f.Price.Min, Max: f.Price.Max}}}
+	case f.Equals != nil:
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Equals{Equals: &pb.ProductFilter_FieldEquals{Field: f.Equals.Field, Value: f.Equals.Value}}}
+	case f.Statuses != nil:
+		statuses := []string{}
+		for _, status := range f.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Statuses_{Statuses: &pb.ProductFilter_Statuses{Statuses: statuses}}}
+	case f.Attribute != nil:
+		return &pb.ProductFilter{Expr: &pb.ProductFilter_Attribute{Attribute: &pb.ProductFilter_AttributeCondition{
+			Name:   f.Attribute.Name,
+			Values: f.Attribute.Values,
+			Min:    f.Attribute.Min,
+			Max:    f.Attribute.Max,
+		}}}
+	}
+	return nil
+}
+
+func filtersToProto(fs []ProductFilter) []*pb.ProductFilter {
+	filters := []*pb.ProductFilter{}
+	for _, f := range fs {
+		if p := filterToProto(f); p != nil {
+			filters = append(filters, p)
+		}
+	}
+	return filters
+}
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+	"github.com/timothydzokoto/grpc_graphql_microservice/order"
 	"github.com/tinrab/retry"
 )
 
 type Config struct {
-	DatabaseUrl string `envconfig:"DATABASE_URL"`
+	DatabaseUrl      string        `envconfig:"DATABASE_URL"`
+	OrderUrl         string        `envconfig:"ORDER_SERVICE_URL"`
+	MediaDir         string        `envconfig:"MEDIA_DIR" default:"/var/lib/catalog/media"`
+	MediaUrl         string        `envconfig:"MEDIA_URL" default:"http://localhost:8081/media"`
+	CacheSize        int           `envconfig:"CACHE_SIZE" default:"10000"`
+	CacheTTL         time.Duration `envconfig:"CACHE_TTL" default:"5m"`
+	ScheduleInterval time.Duration `envconfig:"SCHEDULE_INTERVAL" default:"1m"`
 }
 
 func main() {
@@ -28,9 +37,35 @@ func main() {
 		return
 	})
 	defer r.Close()
+
+	cache := catalog.NewCachedRepository(r, cfg.CacheSize, cfg.CacheTTL)
+	go func() {
+		for range time.Tick(time.Minute) {
+			stats := cache.Stats()
+			log.Printf("Product cache: %d hits, %d misses, %d entries", stats.Hits, stats.Misses, stats.Size)
+		}
+	}()
+
+	st, err := catalog.NewLocalStorage(cfg.MediaDir, cfg.MediaUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	orderClient, err := order.NewClient(cfg.OrderUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer orderClient.Close()
+
+	// Media
+	go func() {
+		http.Handle("/media/", http.StripPrefix("/media/", catalog.MediaHandler(cfg.MediaDir)))
+		log.Fatal(http.ListenAndServe(":8081", nil))
+	}()
 	log.Println("Listening on port 8080")
 
 	// Service
-	s := catalog.NewService(r)
+	s := catalog.NewService(cache, st, orderClient)
+	go s.RunScheduler(context.Background(), cfg.ScheduleInterval)
 	log.Fatal(catalog.ListenGRPC(s, 8080))
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+)
+
+type Config struct {
+	CatalogUrl string `envconfig:"CATALOG_SERVICE_URL"`
+}
+
+func main() {
+	out := flag.String("o", "catalog.ndjson", "file to write the products to, one JSON document per line")
+	resume := flag.Bool("resume", false, "continue an interrupted export into the same file")
+	flag.Parse()
+
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := catalog.NewClient(cfg.CatalogUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	after := ""
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if *resume {
+		if after, err = lastExportedID(*out); err != nil {
+			log.Fatal(err)
+		}
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(*out, mode, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+
+	count := 0
+	err = client.ExportProducts(context.Background(), after, func(p catalog.Product) error {
+		line, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("Export stopped after %d products, rerun with -resume to continue: %v", count, err)
+	}
+
+	log.Printf("Exported %d products to %s", count, *out)
+}
+
+// lastExportedID returns the id of the last complete line of an earlier
+// export. A trailing line that was only partly written is cut off so the
+// export can append right after the last good one.
+func lastExportedID(path string) (string, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Walk backwards until the buffer holds the last newline and the one
+	// before it, or the start of the file.
+	const chunk = 64 * 1024
+	size := info.Size()
+	buf := []byte{}
+	offset := size
+	for offset > 0 && bytes.Count(buf, []byte{'\n'}) < 2 {
+		n := int64(chunk)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		part := make([]byte, n)
+		if _, err := f.ReadAt(part, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		buf = append(part, buf...)
+	}
+
+	end := bytes.LastIndexByte(buf, '\n') + 1
+	if err := f.Truncate(offset + int64(end)); err != nil {
+		return "", err
+	}
+	if end == 0 {
+		return "", nil
+	}
+
+	last := buf[bytes.LastIndexByte(buf[:end-1], '\n')+1 : end-1]
+	p := catalog.Product{}
+	if err := json.Unmarshal(last, &p); err != nil {
+		return "", err
+	}
+	return p.ID, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+)
+
+type Config struct {
+	CatalogUrl string `envconfig:"CATALOG_SERVICE_URL"`
+}
+
+func main() {
+	in := flag.String("i", "catalog.ndjson", "file written by export, one JSON document per line")
+	batchSize := flag.Int("batch", 100, "products sent per request")
+	resume := flag.Bool("resume", false, "skip the lines an interrupted import already applied")
+	dryRun := flag.Bool("dry-run", false, "only report what the import would change")
+	flag.Parse()
+
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := catalog.NewClient(cfg.CatalogUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	if *dryRun {
+		if err := diff(client, *in); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	checkpoint := *in + ".checkpoint"
+	skip := 0
+	if *resume {
+		if skip, err = readCheckpoint(checkpoint); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	imported := 0
+	batch := []catalog.Product{}
+	flush := func(line int) error {
+		if len(batch) > 0 {
+			if err := client.ImportProducts(context.Background(), batch); err != nil {
+				return err
+			}
+			imported += len(batch)
+			batch = batch[:0]
+		}
+		return os.WriteFile(checkpoint, []byte(strconv.Itoa(line)), 0644)
+	}
+
+	last := skip
+	err = readProducts(*in, skip, func(line int, p catalog.Product) error {
+		batch = append(batch, p)
+		last = line
+		if len(batch) < *batchSize {
+			return nil
+		}
+		return flush(line)
+	})
+	if err == nil {
+		err = flush(last)
+	}
+	if err != nil {
+		log.Fatalf("Import stopped after %d products, rerun with -resume to continue: %v", imported, err)
+	}
+
+	os.Remove(checkpoint)
+	log.Printf("Imported %d products from %s", imported, *in)
+}
+
+// readProducts calls fn for every product in the file after the first skip
+// lines, passing the line number it came from.
+func readProducts(path string, skip int, fn func(line int, p catalog.Product) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if line <= skip || len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		p := catalog.Product{}
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if err := fn(line, p); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func readCheckpoint(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// diff compares the file against what the catalog holds right now and
+// prints every product the import would create or change, and every slug
+// it would fail on because another product has or had it.
+func diff(client *catalog.Client, path string) error {
+	current := map[string]catalog.Product{}
+	err := client.ExportProducts(context.Background(), "", func(p catalog.Product) error {
+		current[p.ID] = p
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	created, changed, unchanged, conflicts := 0, 0, 0, 0
+	seen := map[string]bool{}
+	owners := map[string]string{}
+	err = readProducts(path, 0, func(line int, p catalog.Product) error {
+		seen[p.ID] = true
+
+		if p.Slug != "" && p.Slug != current[p.ID].Slug {
+			owner, ok := owners[p.Slug]
+			if !ok {
+				var err error
+				if owner, err = client.SlugOwner(context.Background(), p.Slug); err != nil {
+					return err
+				}
+			}
+			if owner != "" && owner != p.ID {
+				conflicts++
+				fmt.Printf("! %s %q: slug %s belongs to %s\n", p.ID, p.Name, p.Slug, owner)
+			} else {
+				owners[p.Slug] = p.ID
+			}
+		}
+
+		old, ok := current[p.ID]
+		if !ok {
+			created++
+			fmt.Printf("+ %s %q\n", p.ID, p.Name)
+			return nil
+		}
+
+		fields, err := changedFields(old, p)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			unchanged++
+			return nil
+		}
+		changed++
+		fmt.Printf("~ %s %q: %s\n", p.ID, p.Name, strings.Join(fields, ", "))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d to create, %d to change, %d unchanged, %d only in the catalog (kept)\n",
+		created, changed, unchanged, len(current)-len(seen)+created)
+	if conflicts > 0 {
+		fmt.Printf("%d slugs taken by other products, the import would fail\n", conflicts)
+	}
+	return nil
+}
+
+func changedFields(a catalog.Product, b catalog.Product) ([]string, error) {
+	am, err := fieldMap(a)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := fieldMap(b)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for k, v := range bm {
+		if !bytes.Equal(am[k], v) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func fieldMap(p catalog.Product) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]json.RawMessage{}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+	"github.com/timothydzokoto/grpc_graphql_microservice/order"
+)
+
+type Config struct {
+	CatalogUrl string `envconfig:"CATALOG_SERVICE_URL"`
+	OrderUrl   string `envconfig:"ORDER_SERVICE_URL"`
+}
+
+const batchSize = 500
+
+func main() {
+	snapshot := flag.String("restore", "", "export file to restore missing products from")
+	fromOrders := flag.Bool("from-orders", false, "restore products still missing as drafts from their latest order line")
+	flag.Parse()
+
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	catalogClient, err := catalog.NewClient(cfg.CatalogUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer catalogClient.Close()
+
+	orderClient, err := order.NewClient(cfg.OrderUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer orderClient.Close()
+
+	ctx := context.Background()
+
+	missing, err := findMissing(ctx, catalogClient, orderClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, ref := range missing {
+		fmt.Printf("%s missing from catalog, referenced by %d orders\n", ref.ProductID, ref.Orders)
+	}
+	fmt.Printf("%d ordered products missing from catalog\n", len(missing))
+
+	wanted := map[string]bool{}
+	for _, ref := range missing {
+		wanted[ref.ProductID] = true
+	}
+
+	if *snapshot != "" && len(wanted) > 0 {
+		restored, err := restore(ctx, catalogClient, *snapshot, wanted)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d restored from %s, %d not in the snapshot\n", restored, *snapshot, len(wanted))
+	}
+
+	if *fromOrders && len(wanted) > 0 {
+		restored, err := restoreFromOrders(ctx, catalogClient, missing, wanted)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d restored from order lines, %d without a snapshot\n", restored, len(wanted))
+	}
+}
+
+// findMissing walks every product id referenced by an order and returns the
+// ones the catalog does not know.
+func findMissing(ctx context.Context, catalogClient *catalog.Client, orderClient *order.Client) ([]order.ProductReference, error) {
+	missing := []order.ProductReference{}
+	after := ""
+	for {
+		refs, err := orderClient.OrderedProducts(ctx, after, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(refs) == 0 {
+			return missing, nil
+		}
+
+		ids := []string{}
+		for _, ref := range refs {
+			ids = append(ids, ref.ProductID)
+		}
+		products, err := catalogClient.GetAllProducts(ctx, 0, 0, "", ids)
+		if err != nil {
+			return nil, err
+		}
+
+		found := map[string]bool{}
+		for _, p := range products {
+			found[p.ID] = true
+		}
+		for _, ref := range refs {
+			if !found[ref.ProductID] {
+				missing = append(missing, ref)
+			}
+		}
+
+		after = refs[len(refs)-1].ProductID
+	}
+}
+
+// restore imports the wanted products from an export file, keeping their ids
+// so existing orders point at them again.
+func restore(ctx context.Context, client *catalog.Client, path string, wanted map[string]bool) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	products := []catalog.Product{}
+	for scanner.Scan() {
+		p := catalog.Product{}
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			continue
+		}
+		if wanted[p.ID] {
+			products = append(products, p)
+			delete(wanted, p.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return importProducts(ctx, client, products)
+}
+
+// restoreFromOrders recreates the wanted products from the snapshot their
+// latest order line kept. They come back as drafts, the snapshot has no
+// images or other details worth publishing as is.
+func restoreFromOrders(ctx context.Context, client *catalog.Client, refs []order.ProductReference, wanted map[string]bool) (int, error) {
+	products := []catalog.Product{}
+	for _, ref := range refs {
+		if !wanted[ref.ProductID] || ref.Name == "" {
+			continue
+		}
+		products = append(products, catalog.Product{
+			ID:          ref.ProductID,
+			Name:        ref.Name,
+			Description: ref.Description,
+			Price:       ref.Price,
+			Images:      []catalog.Image{},
+			Status:      catalog.StatusDraft,
+		})
+		delete(wanted, ref.ProductID)
+	}
+
+	return importProducts(ctx, client, products)
+}
+
+func importProducts(ctx context.Context, client *catalog.Client, products []catalog.Product) (int, error) {
+	for start := 0; start < len(products); start += batchSize {
+		end := start + batchSize
+		if end > len(products) {
+			end = len(products)
+		}
+		if err := client.ImportProducts(ctx, products[start:end]); err != nil {
+			return start, err
+		}
+	}
+
+	return len(products), nil
+}
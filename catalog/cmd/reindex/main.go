@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+)
+
+type Config struct {
+	DatabaseUrl string `envconfig:"DATABASE_URL"`
+}
+
+func main() {
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := catalog.NewReindexRepository(cfg.DatabaseUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	ctx := context.Background()
+	if err := r.Reindex(ctx); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Reindex complete")
+
+	n, err := catalog.NewService(r, nil, nil).BackfillSlugs(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Gave %d products a slug", n)
+}
@@ -0,0 +1,209 @@
+package catalog
+
+import (
+	"errors"
+	"strconv"
+
+	"gopkg.in/olivere/elastic.v6"
+)
+
+var ErrInvalidFilter = errors.New("Invalid product filter")
+
+// ProductFilter is a boolean expression over products. Exactly one of its
+// fields is set; And and Or combine nested filters.
+type ProductFilter struct {
+	And   []ProductFilter `json:"and,omitempty"`
+	Or    []ProductFilter `json:"or,omitempty"`
+	IDs   []string        `json:"ids,omitempty"`
+	Query string          `json:"query,omitempty"`
+	// Locale makes Query search the translations in that locale as well.
+	Locale string       `json:"locale,omitempty"`
+	Price  *PriceRange  `json:"price,omitempty"`
+	Equals *FieldEquals `json:"equals,omitempty"`
+	// Statuses matches products in any of the given publishing states.
+	Statuses  []ProductStatus     `json:"statuses,omitempty"`
+	Attribute *AttributeCondition `json:"attribute,omitempty"`
+}
+
+// AttributeCondition matches products whose attribute has one of Values, or
+// for numbers lies within Min and Max.
+type AttributeCondition struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+// PriceRange bounds are inclusive, a nil bound is open.
+type PriceRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+type FieldEquals struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// filterFields are the fields FieldEquals may compare, mapped to whether they
+// hold numbers.
+var filterFields = map[string]bool{
+	"name":           false,
+	"description":    false,
+	"price":          true,
+	"rating.average": true,
+	"rating.count":   true,
+	// Finds the bundles containing a product.
+	"bundle.components.product_id": false,
+}
+
+func (f ProductFilter) IsEmpty() bool {
+	return f.And == nil && f.Or == nil && f.IDs == nil && f.Query == "" && f.Price == nil && f.Equals == nil && f.Statuses == nil && f.Attribute == nil
+}
+
+// HasQuery reports whether the filter contains a text query anywhere, in
+// which case results carry meaningful scores and highlights.
+func (f ProductFilter) HasQuery() bool {
+	if f.Query != "" {
+		return true
+	}
+	for _, c := range append(f.And, f.Or...) {
+		if c.HasQuery() {
+			return true
+		}
+	}
+	return false
+}
+
+// withLocale sets the locale of every text query in the filter.
+func (f ProductFilter) withLocale(locale string) ProductFilter {
+	if f.Query != "" {
+		f.Locale = locale
+	}
+	if f.And != nil {
+		and := []ProductFilter{}
+		for _, c := range f.And {
+			and = append(and, c.withLocale(locale))
+		}
+		f.And = and
+	}
+	if f.Or != nil {
+		or := []ProductFilter{}
+		for _, c := range f.Or {
+			or = append(or, c.withLocale(locale))
+		}
+		f.Or = or
+	}
+	return f
+}
+
+// esQuery translates the filter into a single Elasticsearch query. Text
+// queries score, everything else only filters.
+func (f ProductFilter) esQuery() (elastic.Query, error) {
+	switch {
+	case f.And != nil:
+		q := elastic.NewBoolQuery()
+		for _, c := range f.And {
+			cq, err := c.esQuery()
+			if err != nil {
+				return nil, err
+			}
+			if c.HasQuery() {
+				q.Must(cq)
+			} else {
+				q.Filter(cq)
+			}
+		}
+		return q, nil
+
+	case f.Or != nil:
+		q := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+		for _, c := range f.Or {
+			cq, err := c.esQuery()
+			if err != nil {
+				return nil, err
+			}
+			q.Should(cq)
+		}
+		return q, nil
+
+	case f.IDs != nil:
+		return elastic.NewIdsQuery(productType).Ids(f.IDs...), nil
+
+	case f.Query != "":
+		fields := []string{"name", "description"}
+		if locale := normalizeLocale(f.Locale); supportedLocales[locale] != "" {
+			fields = append(fields, "translations."+locale+".name", "translations."+locale+".description")
+		}
+		return elastic.NewMultiMatchQuery(f.Query, fields...).Fuzziness("AUTO"), nil
+
+	case f.Price != nil:
+		q := elastic.NewRangeQuery("price")
+		if f.Price.Min != nil {
+			q.Gte(*f.Price.Min)
+		}
+		if f.Price.Max != nil {
+			q.Lte(*f.Price.Max)
+		}
+		return q, nil
+
+	case f.Equals != nil:
+		numeric, ok := filterFields[f.Equals.Field]
+		if !ok {
+			return nil, ErrInvalidFilter
+		}
+		if !numeric {
+			return elastic.NewMatchPhraseQuery(f.Equals.Field, f.Equals.Value), nil
+		}
+		v, err := strconv.ParseFloat(f.Equals.Value, 64)
+		if err != nil {
+			return nil, ErrInvalidFilter
+		}
+		return elastic.NewTermQuery(f.Equals.Field, v), nil
+
+	case f.Statuses != nil:
+		q := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+		for _, status := range f.Statuses {
+			if !status.valid() {
+				return nil, ErrInvalidFilter
+			}
+			q.Should(elastic.NewTermQuery("status", string(status)))
+			if status == StatusPublished {
+				// Documents from before publishing existed have no status
+				// and were live.
+				q.Should(elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery("status")))
+			}
+		}
+		return q, nil
+
+	case f.Attribute != nil:
+		if !attributeName.MatchString(f.Attribute.Name) {
+			return nil, ErrInvalidFilter
+		}
+		field := "attributes." + f.Attribute.Name
+		q := elastic.NewBoolQuery()
+		if len(f.Attribute.Values) > 0 {
+			values := []interface{}{}
+			for _, v := range f.Attribute.Values {
+				values = append(values, v)
+			}
+			q.Filter(elastic.NewTermsQuery(field, values...))
+		}
+		if f.Attribute.Min != nil || f.Attribute.Max != nil {
+			r := elastic.NewRangeQuery(field)
+			if f.Attribute.Min != nil {
+				r.Gte(*f.Attribute.Min)
+			}
+			if f.Attribute.Max != nil {
+				r.Lte(*f.Attribute.Max)
+			}
+			q.Filter(r)
+		}
+		if len(f.Attribute.Values) == 0 && f.Attribute.Min == nil && f.Attribute.Max == nil {
+			q.Filter(elastic.NewExistsQuery(field))
+		}
+		return q, nil
+	}
+
+	return elastic.NewMatchAllQuery(), nil
+}
@@ -0,0 +1,512 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopkg.in/olivere/elastic.v6"
+)
+
+const (
+	// indexAlias is the name every read and write goes through. It always
+	// points at exactly one versioned index.
+	indexAlias  = "catalog"
+	productType = "product"
+
+	reviewIndex = "catalog_reviews"
+	reviewType  = "review"
+
+	changeIndex = "catalog_changes"
+	changeType  = "change"
+
+	synonymIndex = "catalog_synonyms"
+	synonymType  = "synonym"
+
+	attributeIndex = "catalog_attributes"
+	attributeType  = "attribute"
+
+	// slugIndex maps every slug a product ever had to its id.
+	slugIndex = "catalog_slugs"
+	slugType  = "slug"
+
+	// mappingVersion must be bumped whenever productMapping changes. The
+	// service refuses to start on an index with another version until the
+	// reindex command has rebuilt it.
+	mappingVersion = 10
+)
+
+// productMapping is completed with the JSON list of synonym rules. Synonyms
+// only apply at search time but are baked into the index settings, so
+// changing them takes a reindex.
+const productMapping = `{
+	"settings": {
+		"number_of_shards": 1,
+		"analysis": {
+			"filter": {
+				"product_synonyms": {
+					"type": "synonym_graph",
+					"synonyms": %s
+				}
+			},
+			"analyzer": {
+				"product_text": {
+					"type": "custom",
+					"tokenizer": "standard",
+					"filter": ["lowercase", "asciifolding"]
+				},
+				"product_search": {
+					"type": "custom",
+					"tokenizer": "standard",
+					"filter": ["lowercase", "asciifolding", "product_synonyms"]
+				}
+			}
+		}
+	},
+	"mappings": {
+		"product": {
+			"dynamic": "strict",
+			"dynamic_templates": [
+				{"attribute_strings": {"path_match": "attributes.*", "match_mapping_type": "string", "mapping": {"type": "keyword"}}},
+				{"attribute_longs": {"path_match": "attributes.*", "match_mapping_type": "long", "mapping": {"type": "double"}}},
+				{"attribute_doubles": {"path_match": "attributes.*", "match_mapping_type": "double", "mapping": {"type": "double"}}}
+			],
+			"properties": {
+				"slug": {"type": "keyword"},
+				"name": {"type": "text", "analyzer": "product_text", "search_analyzer": "product_search", "copy_to": "suggest"},
+				"description": {"type": "text", "analyzer": "product_text", "search_analyzer": "product_search", "copy_to": "suggest"},
+				"suggest": {"type": "text", "analyzer": "product_text"},
+				"price": {"type": "double"},
+				"images": {"type": "object", "enabled": false},
+				"rating": {
+					"properties": {
+						"average": {"type": "double"},
+						"count": {"type": "long"}
+					}
+				},
+				"status": {"type": "keyword"},
+				"publish_at": {"type": "date"},
+				"unpublish_at": {"type": "date"},
+				"attributes": {"type": "object", "dynamic": true},
+				"change_sequence": {"type": "long"},
+				"bundle": {
+					"properties": {
+						"components": {
+							"properties": {
+								"product_id": {"type": "keyword"},
+								"quantity": {"type": "long"}
+							}
+						},
+						"pricing": {"type": "keyword"},
+						"discount": {"type": "double"}
+					}
+				},
+				"translations": {
+					"properties": {
+						"fr": {
+							"properties": {
+								"name": {"type": "text", "analyzer": "french"},
+								"description": {"type": "text", "analyzer": "french"}
+							}
+						},
+						"de": {
+							"properties": {
+								"name": {"type": "text", "analyzer": "german"},
+								"description": {"type": "text", "analyzer": "german"}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+const reviewMapping = `{
+	"settings": {
+		"number_of_shards": 1
+	},
+	"mappings": {
+		"review": {
+			"dynamic": "strict",
+			"properties": {
+				"id": {"type": "keyword"},
+				"product_id": {"type": "keyword"},
+				"account_id": {"type": "keyword"},
+				"rating": {"type": "integer"},
+				"body": {"type": "text"},
+				"created_at": {"type": "date"}
+			}
+		}
+	}
+}`
+
+const changeMapping = `{
+	"settings": {
+		"number_of_shards": 1
+	},
+	"mappings": {
+		"change": {
+			"dynamic": "strict",
+			"properties": {
+				"sequence": {"type": "long"},
+				"type": {"type": "keyword"},
+				"product_id": {"type": "keyword"},
+				"product": {"type": "object", "enabled": false},
+				"created_at": {"type": "date"}
+			}
+		}
+	}
+}`
+
+const synonymMapping = `{
+	"settings": {
+		"number_of_shards": 1
+	},
+	"mappings": {
+		"synonym": {
+			"dynamic": "strict",
+			"properties": {
+				"id": {"type": "keyword"},
+				"terms": {"type": "keyword"}
+			}
+		}
+	}
+}`
+
+const attributeMapping = `{
+	"settings": {
+		"number_of_shards": 1
+	},
+	"mappings": {
+		"attribute": {
+			"dynamic": "strict",
+			"properties": {
+				"name": {"type": "keyword"},
+				"type": {"type": "keyword"},
+				"values": {"type": "keyword"},
+				"deleted": {"type": "boolean"}
+			}
+		}
+	}
+}`
+
+const slugMapping = `{
+	"settings": {
+		"number_of_shards": 1
+	},
+	"mappings": {
+		"slug": {
+			"dynamic": "strict",
+			"properties": {
+				"slug": {"type": "keyword"},
+				"product_id": {"type": "keyword"},
+				"created_at": {"type": "date"}
+			}
+		}
+	}
+}`
+
+func newIndexName() string {
+	return fmt.Sprintf("%s_v%d_%d", indexAlias, mappingVersion, time.Now().UTC().UnixNano())
+}
+
+func isCurrentIndex(name string) bool {
+	return strings.HasPrefix(name, fmt.Sprintf("%s_v%d_", indexAlias, mappingVersion))
+}
+
+// ensureIndex creates the alias and its index on a fresh cluster. Every
+// replica runs it on startup, so an existing index that is out of date is
+// never rebuilt here. It fails with ErrIndexOutOfDate when requireCurrent
+// is set, the strict mapping would reject writes of new fields.
+func (r *elasticsearchRepository) ensureIndex(ctx context.Context, requireCurrent bool) error {
+	indices, err := r.aliasedIndices(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(indices) == 0 {
+		legacy, err := r.client.IndexExists(indexAlias).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if legacy {
+			if requireCurrent {
+				return fmt.Errorf("%w: %q is not behind an alias yet", ErrIndexOutOfDate, indexAlias)
+			}
+			return nil
+		}
+
+		// Replicas starting together all get here. The fixed name lets one
+		// of them create the index, the others only add the alias to it.
+		name := fmt.Sprintf("%s_v%d_0", indexAlias, mappingVersion)
+		if err := r.createIndex(ctx, name); err != nil && !isAlreadyExists(err) {
+			return err
+		}
+		_, err = r.client.Alias().Add(name, indexAlias).Do(ctx)
+		return err
+	}
+
+	if len(indices) == 1 && isCurrentIndex(indices[0]) {
+		return nil
+	}
+
+	if requireCurrent {
+		return fmt.Errorf("%w: %v is not version %d", ErrIndexOutOfDate, indices, mappingVersion)
+	}
+	return nil
+}
+
+func isAlreadyExists(err error) bool {
+	e, ok := err.(*elastic.Error)
+	return ok && e.Details != nil && e.Details.Type == "resource_already_exists_exception"
+}
+
+// Reindex builds a fresh index with the current mapping, copies every
+// document into it and atomically moves the alias over. Writes that land on
+// the old index while the copy runs are picked up by a second pass after the
+// swap, deletes by replaying them from the change log. A legacy index
+// squatting on the alias name is migrated instead.
+func (r *elasticsearchRepository) Reindex(ctx context.Context) error {
+	oldIndices, err := r.aliasedIndices(ctx)
+	if err != nil {
+		return err
+	}
+	if len(oldIndices) == 0 {
+		legacy, err := r.client.IndexExists(indexAlias).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if !legacy {
+			return fmt.Errorf("alias %q does not exist", indexAlias)
+		}
+		log.Printf("Migrating legacy index %q", indexAlias)
+		return r.migrateLegacyIndex(ctx)
+	}
+
+	since, err := r.LastChangeSequence(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := newIndexName()
+	if err := r.createIndex(ctx, name); err != nil {
+		return err
+	}
+
+	if err := r.copyDocuments(ctx, indexAlias, name); err != nil {
+		r.client.DeleteIndex(name).Do(ctx)
+		return err
+	}
+
+	swap := r.client.Alias().Add(name, indexAlias)
+	for _, old := range oldIndices {
+		swap = swap.Remove(old, indexAlias)
+	}
+	if _, err := swap.Do(ctx); err != nil {
+		r.client.DeleteIndex(name).Do(ctx)
+		return err
+	}
+
+	for _, old := range oldIndices {
+		if err := r.copyDocuments(ctx, old, name); err != nil {
+			return err
+		}
+	}
+	if err := r.replayDeletes(ctx, name, since); err != nil {
+		return err
+	}
+
+	if _, err := r.client.DeleteIndex(oldIndices...).Do(ctx); err != nil {
+		log.Println("Error deleting old indices: ", err)
+	}
+
+	log.Printf("Alias %q now points at %q", indexAlias, name)
+	return nil
+}
+
+// ensureSideIndex creates one of the unversioned indices that live next to
+// the product index, or adds the fields its mapping gained since.
+func (r *elasticsearchRepository) ensureSideIndex(ctx context.Context, name string, mapping string) error {
+	exists, err := r.client.IndexExists(name).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return r.updateSideMapping(ctx, name, mapping)
+	}
+
+	_, err = r.client.CreateIndex(name).
+		IncludeTypeName(true).
+		BodyString(mapping).
+		Do(ctx)
+
+	if isAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// updateSideMapping puts the mapping of a side index again. That only adds
+// fields, side indices never change the ones they have.
+func (r *elasticsearchRepository) updateSideMapping(ctx context.Context, name string, mapping string) error {
+	var m struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(mapping), &m); err != nil {
+		return err
+	}
+
+	for typ, body := range m.Mappings {
+		_, err := r.client.PutMapping().
+			Index(name).
+			Type(typ).
+			IncludeTypeName(true).
+			BodyString(string(body)).
+			Do(ctx)
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *elasticsearchRepository) migrateLegacyIndex(ctx context.Context) error {
+	name := newIndexName()
+	if err := r.createIndex(ctx, name); err != nil {
+		return err
+	}
+
+	if err := r.copyDocuments(ctx, indexAlias, name); err != nil {
+		r.client.DeleteIndex(name).Do(ctx)
+		return err
+	}
+
+	// Deleting the legacy index frees its name for the alias. Both happen
+	// in one atomic aliases call, readers never find the name missing.
+	_, err := r.client.Alias().
+		Action(
+			elastic.NewAliasRemoveIndexAction(indexAlias),
+			elastic.NewAliasAddAction(indexAlias).Index(name),
+		).
+		Do(ctx)
+
+	return err
+}
+
+func (r *elasticsearchRepository) createIndex(ctx context.Context, name string) error {
+	synonyms, err := r.ListSynonyms(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := []string{}
+	for _, s := range synonyms {
+		rules = append(rules, s.rule())
+	}
+	body, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.CreateIndex(name).
+		IncludeTypeName(true).
+		BodyString(fmt.Sprintf(productMapping, body)).
+		Do(ctx)
+
+	return err
+}
+
+func (r *elasticsearchRepository) copyDocuments(ctx context.Context, from string, to string) error {
+	res, err := r.client.Reindex().
+		SourceIndex(from).
+		Destination(elastic.NewReindexDestination().
+			Index(to).
+			Type(productType).
+			VersionType("external")).
+		Conflicts("proceed").
+		Refresh("true").
+		WaitForCompletion(true).
+		Do(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if len(res.Failures) > 0 {
+		return fmt.Errorf("reindex from %q to %q: %d failures", from, to, len(res.Failures))
+	}
+
+	return nil
+}
+
+// replayDeletes deletes from index the products deleted after change since,
+// unless they were written again after their delete.
+func (r *elasticsearchRepository) replayDeletes(ctx context.Context, index string, since uint64) error {
+	for {
+		changes, err := r.ListChanges(ctx, since, 1000)
+		if err != nil || len(changes) == 0 {
+			return err
+		}
+
+		for _, c := range changes {
+			since = c.Sequence
+			if c.Type != ChangeDeleted {
+				continue
+			}
+
+			res, err := r.client.Get().
+				Index(index).
+				Type(productType).
+				Id(c.ProductID).
+				Do(ctx)
+
+			if elastic.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !res.Found || res.SeqNo == nil || res.PrimaryTerm == nil {
+				continue
+			}
+
+			doc := ProductDocument{}
+			if err = json.Unmarshal(*res.Source, &doc); err != nil {
+				return err
+			}
+			if doc.ChangeSequence > c.Sequence {
+				continue
+			}
+
+			// A write that wins the race carries a later sequence.
+			_, err = r.client.Delete().
+				Index(index).
+				Type(productType).
+				Id(c.ProductID).
+				IfSeqNo(*res.SeqNo).
+				IfPrimaryTerm(*res.PrimaryTerm).
+				Do(ctx)
+
+			if err != nil && !elastic.IsConflict(err) && !elastic.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+}
+
+func (r *elasticsearchRepository) aliasedIndices(ctx context.Context) ([]string, error) {
+	res, err := r.client.Aliases().Alias(indexAlias).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return res.IndicesByAlias(indexAlias), nil
+}
@@ -0,0 +1,120 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the language of the top level name and description.
+const DefaultLocale = "en"
+
+// supportedLocales maps each locale products can be translated to onto the
+// Elasticsearch analyzer of its language. productMapping declares one
+// translations field per entry.
+var supportedLocales = map[string]string{
+	"fr": "french",
+	"de": "german",
+}
+
+var ErrUnsupportedLocale = errors.New("Unsupported locale")
+
+type Translation struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type localeKey struct{}
+
+// WithLocale makes the catalog client ask for products in locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+func localeFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// normalizeLocale reduces a language tag like "fr-CA" to its language.
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+// ParseAcceptLanguage returns the supported locale an Accept-Language header
+// prefers most, or the default locale.
+func ParseAcceptLanguage(header string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	candidates := []candidate{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		c := candidate{locale: normalizeLocale(fields[0]), q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					c.q = q
+				}
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, c := range candidates {
+		if c.q <= 0 {
+			continue
+		}
+		if _, ok := supportedLocales[c.locale]; ok || c.locale == DefaultLocale {
+			return c.locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Localized returns p with name and description in locale. Each field falls
+// back to the default locale when it has no translation.
+func (p Product) Localized(locale string) Product {
+	t, ok := p.Translations[normalizeLocale(locale)]
+	if !ok {
+		return p
+	}
+	if t.Name != "" {
+		p.Name = t.Name
+	}
+	if t.Description != "" {
+		p.Description = t.Description
+	}
+	return p
+}
+
+// SetProductTranslations replaces all translations of a product.
+func (s *catalogService) SetProductTranslations(ctx context.Context, id string, translations map[string]Translation) (*Product, error) {
+	normalized := map[string]Translation{}
+	for locale, t := range translations {
+		locale = normalizeLocale(locale)
+		if _, ok := supportedLocales[locale]; !ok {
+			return nil, ErrUnsupportedLocale
+		}
+		normalized[locale] = t
+	}
+
+	if err := s.repository.UpdateProductTranslations(ctx, id, normalized); err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	return s.repository.GetProductByID(ctx, id)
+}
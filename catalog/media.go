@@ -0,0 +1,167 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	maxImageSize = 10 << 20
+	// maxImagePixels bounds width times height, a small file can still
+	// decode to a huge image.
+	maxImagePixels = 25_000_000
+)
+
+var (
+	ErrUnsupportedMedia = errors.New("Unsupported media type")
+	ErrMediaTooLarge    = errors.New("Media too large")
+)
+
+type Image struct {
+	ID          string      `json:"id"`
+	URL         string      `json:"url"`
+	AltText     string      `json:"alt_text"`
+	Position    int         `json:"position"`
+	ContentType string      `json:"content_type"`
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Thumbnails  []Thumbnail `json:"thumbnails"`
+}
+
+type Thumbnail struct {
+	Size   string `json:"size"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// thumbnailSizes are the bounding boxes generated for every uploaded image.
+// Images smaller than a box are not scaled up.
+var thumbnailSizes = []struct {
+	Name string
+	Max  int
+}{
+	{"small", 160},
+	{"medium", 480},
+	{"large", 1024},
+}
+
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+func readImage(r io.Reader) ([]byte, image.Image, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxImageSize+1))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, nil, "", ErrMediaTooLarge
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, nil, "", ErrUnsupportedMedia
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, nil, "", ErrMediaTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, "", ErrUnsupportedMedia
+	}
+
+	contentType := "image/" + format
+	if _, ok := imageExtensions[contentType]; !ok {
+		return nil, nil, "", ErrUnsupportedMedia
+	}
+
+	return data, img, contentType, nil
+}
+
+func scaleImage(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+
+	if w > h {
+		w, h = max, h*max/w
+	} else {
+		w, h = w*max/h, max
+	}
+	if w == 0 {
+		w = 1
+	}
+	if h == 0 {
+		h = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+// encodeImage writes a thumbnail in the format of the original upload. GIFs
+// are stored as PNG since thumbnails drop the animation anyway.
+func encodeImage(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/png", "image/gif":
+		err = png.Encode(&buf, img)
+	default:
+		return nil, ErrUnsupportedMedia
+	}
+
+	return buf.Bytes(), err
+}
+
+func thumbnailExtension(contentType string) string {
+	if contentType == "image/gif" {
+		return imageExtensions["image/png"]
+	}
+
+	return imageExtensions[contentType]
+}
+
+func imageKey(productID string, imageID string, name string, ext string) string {
+	return fmt.Sprintf("products/%s/%s/%s.%s", productID, imageID, name, ext)
+}
+
+// insertImage places img at position and renumbers the list. A negative or
+// out of range position appends.
+func insertImage(images []Image, img Image, position int) []Image {
+	if position < 0 || position > len(images) {
+		position = len(images)
+	}
+
+	images = append(images, Image{})
+	copy(images[position+1:], images[position:])
+	images[position] = img
+
+	return renumberImages(images)
+}
+
+func renumberImages(images []Image) []Image {
+	for i := range images {
+		images[i].Position = i
+	}
+
+	return images
+}
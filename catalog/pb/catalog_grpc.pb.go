@@ -19,9 +19,31 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	CatalogService_PostProduct_FullMethodName = "/pb.CatalogService/PostProduct"
-	CatalogService_GetProduct_FullMethodName  = "/pb.CatalogService/GetProduct"
-	CatalogService_GetProducts_FullMethodName = "/pb.CatalogService/GetProducts"
+	CatalogService_PostProduct_FullMethodName               = "/pb.CatalogService/PostProduct"
+	CatalogService_GetProduct_FullMethodName                = "/pb.CatalogService/GetProduct"
+	CatalogService_GetProducts_FullMethodName               = "/pb.CatalogService/GetProducts"
+	CatalogService_UploadProductImage_FullMethodName        = "/pb.CatalogService/UploadProductImage"
+	CatalogService_UpdateProductImage_FullMethodName        = "/pb.CatalogService/UpdateProductImage"
+	CatalogService_DeleteProductImage_FullMethodName        = "/pb.CatalogService/DeleteProductImage"
+	CatalogService_PostReview_FullMethodName                = "/pb.CatalogService/PostReview"
+	CatalogService_GetReviews_FullMethodName                = "/pb.CatalogService/GetReviews"
+	CatalogService_GetRelatedProducts_FullMethodName        = "/pb.CatalogService/GetRelatedProducts"
+	CatalogService_DeleteProduct_FullMethodName             = "/pb.CatalogService/DeleteProduct"
+	CatalogService_WatchProducts_FullMethodName             = "/pb.CatalogService/WatchProducts"
+	CatalogService_GetSynonyms_FullMethodName               = "/pb.CatalogService/GetSynonyms"
+	CatalogService_PutSynonym_FullMethodName                = "/pb.CatalogService/PutSynonym"
+	CatalogService_DeleteSynonym_FullMethodName             = "/pb.CatalogService/DeleteSynonym"
+	CatalogService_SetProductStatus_FullMethodName          = "/pb.CatalogService/SetProductStatus"
+	CatalogService_ExportProducts_FullMethodName            = "/pb.CatalogService/ExportProducts"
+	CatalogService_ImportProducts_FullMethodName            = "/pb.CatalogService/ImportProducts"
+	CatalogService_GetAttributeDefinitions_FullMethodName   = "/pb.CatalogService/GetAttributeDefinitions"
+	CatalogService_PutAttributeDefinition_FullMethodName    = "/pb.CatalogService/PutAttributeDefinition"
+	CatalogService_DeleteAttributeDefinition_FullMethodName = "/pb.CatalogService/DeleteAttributeDefinition"
+	CatalogService_SetProductAttributes_FullMethodName      = "/pb.CatalogService/SetProductAttributes"
+	CatalogService_SetProductTranslations_FullMethodName    = "/pb.CatalogService/SetProductTranslations"
+	CatalogService_GetProductBySlug_FullMethodName          = "/pb.CatalogService/GetProductBySlug"
+	CatalogService_ChangeProductSlug_FullMethodName         = "/pb.CatalogService/ChangeProductSlug"
+	CatalogService_SetProductBundle_FullMethodName          = "/pb.CatalogService/SetProductBundle"
 )
 
 // CatalogServiceClient is the client API for CatalogService service.
@@ -31,6 +53,28 @@ type CatalogServiceClient interface {
 	PostProduct(ctx context.Context, in *PostProductRequest, opts ...grpc.CallOption) (*PostProductResponse, error)
 	GetProduct(ctx context.Context, in *GetProductRequest, opts ...grpc.CallOption) (*GetProductResponse, error)
 	GetProducts(ctx context.Context, in *GetProductsRequest, opts ...grpc.CallOption) (*GetProductsResponse, error)
+	UploadProductImage(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadProductImageRequest, UploadProductImageResponse], error)
+	UpdateProductImage(ctx context.Context, in *UpdateProductImageRequest, opts ...grpc.CallOption) (*UpdateProductImageResponse, error)
+	DeleteProductImage(ctx context.Context, in *DeleteProductImageRequest, opts ...grpc.CallOption) (*DeleteProductImageResponse, error)
+	PostReview(ctx context.Context, in *PostReviewRequest, opts ...grpc.CallOption) (*PostReviewResponse, error)
+	GetReviews(ctx context.Context, in *GetReviewsRequest, opts ...grpc.CallOption) (*GetReviewsResponse, error)
+	GetRelatedProducts(ctx context.Context, in *GetRelatedProductsRequest, opts ...grpc.CallOption) (*GetRelatedProductsResponse, error)
+	DeleteProduct(ctx context.Context, in *DeleteProductRequest, opts ...grpc.CallOption) (*DeleteProductResponse, error)
+	WatchProducts(ctx context.Context, in *WatchProductsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProductEvent], error)
+	GetSynonyms(ctx context.Context, in *GetSynonymsRequest, opts ...grpc.CallOption) (*GetSynonymsResponse, error)
+	PutSynonym(ctx context.Context, in *PutSynonymRequest, opts ...grpc.CallOption) (*PutSynonymResponse, error)
+	DeleteSynonym(ctx context.Context, in *DeleteSynonymRequest, opts ...grpc.CallOption) (*DeleteSynonymResponse, error)
+	SetProductStatus(ctx context.Context, in *SetProductStatusRequest, opts ...grpc.CallOption) (*SetProductStatusResponse, error)
+	ExportProducts(ctx context.Context, in *ExportProductsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Product], error)
+	ImportProducts(ctx context.Context, in *ImportProductsRequest, opts ...grpc.CallOption) (*ImportProductsResponse, error)
+	GetAttributeDefinitions(ctx context.Context, in *GetAttributeDefinitionsRequest, opts ...grpc.CallOption) (*GetAttributeDefinitionsResponse, error)
+	PutAttributeDefinition(ctx context.Context, in *PutAttributeDefinitionRequest, opts ...grpc.CallOption) (*PutAttributeDefinitionResponse, error)
+	DeleteAttributeDefinition(ctx context.Context, in *DeleteAttributeDefinitionRequest, opts ...grpc.CallOption) (*DeleteAttributeDefinitionResponse, error)
+	SetProductAttributes(ctx context.Context, in *SetProductAttributesRequest, opts ...grpc.CallOption) (*SetProductAttributesResponse, error)
+	SetProductTranslations(ctx context.Context, in *SetProductTranslationsRequest, opts ...grpc.CallOption) (*SetProductTranslationsResponse, error)
+	GetProductBySlug(ctx context.Context, in *GetProductBySlugRequest, opts ...grpc.CallOption) (*GetProductBySlugResponse, error)
+	ChangeProductSlug(ctx context.Context, in *ChangeProductSlugRequest, opts ...grpc.CallOption) (*ChangeProductSlugResponse, error)
+	SetProductBundle(ctx context.Context, in *SetProductBundleRequest, opts ...grpc.CallOption) (*SetProductBundleResponse, error)
 }
 
 type catalogServiceClient struct {
@@ -71,6 +115,247 @@ func (c *catalogServiceClient) GetProducts(ctx context.Context, in *GetProductsR
 	return out, nil
 }
 
+func (c *catalogServiceClient) UploadProductImage(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadProductImageRequest, UploadProductImageResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CatalogService_ServiceDesc.Streams[0], CatalogService_UploadProductImage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadProductImageRequest, UploadProductImageResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CatalogService_UploadProductImageClient = grpc.ClientStreamingClient[UploadProductImageRequest, UploadProductImageResponse]
+
+func (c *catalogServiceClient) UpdateProductImage(ctx context.Context, in *UpdateProductImageRequest, opts ...grpc.CallOption) (*UpdateProductImageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateProductImageResponse)
+	err := c.cc.Invoke(ctx, CatalogService_UpdateProductImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) DeleteProductImage(ctx context.Context, in *DeleteProductImageRequest, opts ...grpc.CallOption) (*DeleteProductImageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteProductImageResponse)
+	err := c.cc.Invoke(ctx, CatalogService_DeleteProductImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) PostReview(ctx context.Context, in *PostReviewRequest, opts ...grpc.CallOption) (*PostReviewResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PostReviewResponse)
+	err := c.cc.Invoke(ctx, CatalogService_PostReview_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetReviews(ctx context.Context, in *GetReviewsRequest, opts ...grpc.CallOption) (*GetReviewsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetReviewsResponse)
+	err := c.cc.Invoke(ctx, CatalogService_GetReviews_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetRelatedProducts(ctx context.Context, in *GetRelatedProductsRequest, opts ...grpc.CallOption) (*GetRelatedProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRelatedProductsResponse)
+	err := c.cc.Invoke(ctx, CatalogService_GetRelatedProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) DeleteProduct(ctx context.Context, in *DeleteProductRequest, opts ...grpc.CallOption) (*DeleteProductResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteProductResponse)
+	err := c.cc.Invoke(ctx, CatalogService_DeleteProduct_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) WatchProducts(ctx context.Context, in *WatchProductsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProductEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CatalogService_ServiceDesc.Streams[1], CatalogService_WatchProducts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchProductsRequest, ProductEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CatalogService_WatchProductsClient = grpc.ServerStreamingClient[ProductEvent]
+
+func (c *catalogServiceClient) GetSynonyms(ctx context.Context, in *GetSynonymsRequest, opts ...grpc.CallOption) (*GetSynonymsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSynonymsResponse)
+	err := c.cc.Invoke(ctx, CatalogService_GetSynonyms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) PutSynonym(ctx context.Context, in *PutSynonymRequest, opts ...grpc.CallOption) (*PutSynonymResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutSynonymResponse)
+	err := c.cc.Invoke(ctx, CatalogService_PutSynonym_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) DeleteSynonym(ctx context.Context, in *DeleteSynonymRequest, opts ...grpc.CallOption) (*DeleteSynonymResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSynonymResponse)
+	err := c.cc.Invoke(ctx, CatalogService_DeleteSynonym_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductStatus(ctx context.Context, in *SetProductStatusRequest, opts ...grpc.CallOption) (*SetProductStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetProductStatusResponse)
+	err := c.cc.Invoke(ctx, CatalogService_SetProductStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) ExportProducts(ctx context.Context, in *ExportProductsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Product], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CatalogService_ServiceDesc.Streams[2], CatalogService_ExportProducts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportProductsRequest, Product]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CatalogService_ExportProductsClient = grpc.ServerStreamingClient[Product]
+
+func (c *catalogServiceClient) ImportProducts(ctx context.Context, in *ImportProductsRequest, opts ...grpc.CallOption) (*ImportProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportProductsResponse)
+	err := c.cc.Invoke(ctx, CatalogService_ImportProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetAttributeDefinitions(ctx context.Context, in *GetAttributeDefinitionsRequest, opts ...grpc.CallOption) (*GetAttributeDefinitionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAttributeDefinitionsResponse)
+	err := c.cc.Invoke(ctx, CatalogService_GetAttributeDefinitions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) PutAttributeDefinition(ctx context.Context, in *PutAttributeDefinitionRequest, opts ...grpc.CallOption) (*PutAttributeDefinitionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutAttributeDefinitionResponse)
+	err := c.cc.Invoke(ctx, CatalogService_PutAttributeDefinition_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) DeleteAttributeDefinition(ctx context.Context, in *DeleteAttributeDefinitionRequest, opts ...grpc.CallOption) (*DeleteAttributeDefinitionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAttributeDefinitionResponse)
+	err := c.cc.Invoke(ctx, CatalogService_DeleteAttributeDefinition_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductAttributes(ctx context.Context, in *SetProductAttributesRequest, opts ...grpc.CallOption) (*SetProductAttributesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetProductAttributesResponse)
+	err := c.cc.Invoke(ctx, CatalogService_SetProductAttributes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductTranslations(ctx context.Context, in *SetProductTranslationsRequest, opts ...grpc.CallOption) (*SetProductTranslationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetProductTranslationsResponse)
+	err := c.cc.Invoke(ctx, CatalogService_SetProductTranslations_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) GetProductBySlug(ctx context.Context, in *GetProductBySlugRequest, opts ...grpc.CallOption) (*GetProductBySlugResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetProductBySlugResponse)
+	err := c.cc.Invoke(ctx, CatalogService_GetProductBySlug_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) ChangeProductSlug(ctx context.Context, in *ChangeProductSlugRequest, opts ...grpc.CallOption) (*ChangeProductSlugResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChangeProductSlugResponse)
+	err := c.cc.Invoke(ctx, CatalogService_ChangeProductSlug_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogServiceClient) SetProductBundle(ctx context.Context, in *SetProductBundleRequest, opts ...grpc.CallOption) (*SetProductBundleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetProductBundleResponse)
+	err := c.cc.Invoke(ctx, CatalogService_SetProductBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CatalogServiceServer is the server API for CatalogService service.
 // All implementations must embed UnimplementedCatalogServiceServer
 // for forward compatibility.
@@ -78,6 +363,28 @@ type CatalogServiceServer interface {
 	PostProduct(context.Context, *PostProductRequest) (*PostProductResponse, error)
 	GetProduct(context.Context, *GetProductRequest) (*GetProductResponse, error)
 	GetProducts(context.Context, *GetProductsRequest) (*GetProductsResponse, error)
+	UploadProductImage(grpc.ClientStreamingServer[UploadProductImageRequest, UploadProductImageResponse]) error
+	UpdateProductImage(context.Context, *UpdateProductImageRequest) (*UpdateProductImageResponse, error)
+	DeleteProductImage(context.Context, *DeleteProductImageRequest) (*DeleteProductImageResponse, error)
+	PostReview(context.Context, *PostReviewRequest) (*PostReviewResponse, error)
+	GetReviews(context.Context, *GetReviewsRequest) (*GetReviewsResponse, error)
+	GetRelatedProducts(context.Context, *GetRelatedProductsRequest) (*GetRelatedProductsResponse, error)
+	DeleteProduct(context.Context, *DeleteProductRequest) (*DeleteProductResponse, error)
+	WatchProducts(*WatchProductsRequest, grpc.ServerStreamingServer[ProductEvent]) error
+	GetSynonyms(context.Context, *GetSynonymsRequest) (*GetSynonymsResponse, error)
+	PutSynonym(context.Context, *PutSynonymRequest) (*PutSynonymResponse, error)
+	DeleteSynonym(context.Context, *DeleteSynonymRequest) (*DeleteSynonymResponse, error)
+	SetProductStatus(context.Context, *SetProductStatusRequest) (*SetProductStatusResponse, error)
+	ExportProducts(*ExportProductsRequest, grpc.ServerStreamingServer[Product]) error
+	ImportProducts(context.Context, *ImportProductsRequest) (*ImportProductsResponse, error)
+	GetAttributeDefinitions(context.Context, *GetAttributeDefinitionsRequest) (*GetAttributeDefinitionsResponse, error)
+	PutAttributeDefinition(context.Context, *PutAttributeDefinitionRequest) (*PutAttributeDefinitionResponse, error)
+	DeleteAttributeDefinition(context.Context, *DeleteAttributeDefinitionRequest) (*DeleteAttributeDefinitionResponse, error)
+	SetProductAttributes(context.Context, *SetProductAttributesRequest) (*SetProductAttributesResponse, error)
+	SetProductTranslations(context.Context, *SetProductTranslationsRequest) (*SetProductTranslationsResponse, error)
+	GetProductBySlug(context.Context, *GetProductBySlugRequest) (*GetProductBySlugResponse, error)
+	ChangeProductSlug(context.Context, *ChangeProductSlugRequest) (*ChangeProductSlugResponse, error)
+	SetProductBundle(context.Context, *SetProductBundleRequest) (*SetProductBundleResponse, error)
 	mustEmbedUnimplementedCatalogServiceServer()
 }
 
@@ -97,6 +404,72 @@ func (UnimplementedCatalogServiceServer) GetProduct(context.Context, *GetProduct
 func (UnimplementedCatalogServiceServer) GetProducts(context.Context, *GetProductsRequest) (*GetProductsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProducts not implemented")
 }
+func (UnimplementedCatalogServiceServer) UploadProductImage(grpc.ClientStreamingServer[UploadProductImageRequest, UploadProductImageResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadProductImage not implemented")
+}
+func (UnimplementedCatalogServiceServer) UpdateProductImage(context.Context, *UpdateProductImageRequest) (*UpdateProductImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProductImage not implemented")
+}
+func (UnimplementedCatalogServiceServer) DeleteProductImage(context.Context, *DeleteProductImageRequest) (*DeleteProductImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteProductImage not implemented")
+}
+func (UnimplementedCatalogServiceServer) PostReview(context.Context, *PostReviewRequest) (*PostReviewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostReview not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetReviews(context.Context, *GetReviewsRequest) (*GetReviewsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReviews not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetRelatedProducts(context.Context, *GetRelatedProductsRequest) (*GetRelatedProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRelatedProducts not implemented")
+}
+func (UnimplementedCatalogServiceServer) DeleteProduct(context.Context, *DeleteProductRequest) (*DeleteProductResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteProduct not implemented")
+}
+func (UnimplementedCatalogServiceServer) WatchProducts(*WatchProductsRequest, grpc.ServerStreamingServer[ProductEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProducts not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetSynonyms(context.Context, *GetSynonymsRequest) (*GetSynonymsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSynonyms not implemented")
+}
+func (UnimplementedCatalogServiceServer) PutSynonym(context.Context, *PutSynonymRequest) (*PutSynonymResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutSynonym not implemented")
+}
+func (UnimplementedCatalogServiceServer) DeleteSynonym(context.Context, *DeleteSynonymRequest) (*DeleteSynonymResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSynonym not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductStatus(context.Context, *SetProductStatusRequest) (*SetProductStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductStatus not implemented")
+}
+func (UnimplementedCatalogServiceServer) ExportProducts(*ExportProductsRequest, grpc.ServerStreamingServer[Product]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportProducts not implemented")
+}
+func (UnimplementedCatalogServiceServer) ImportProducts(context.Context, *ImportProductsRequest) (*ImportProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportProducts not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetAttributeDefinitions(context.Context, *GetAttributeDefinitionsRequest) (*GetAttributeDefinitionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAttributeDefinitions not implemented")
+}
+func (UnimplementedCatalogServiceServer) PutAttributeDefinition(context.Context, *PutAttributeDefinitionRequest) (*PutAttributeDefinitionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutAttributeDefinition not implemented")
+}
+func (UnimplementedCatalogServiceServer) DeleteAttributeDefinition(context.Context, *DeleteAttributeDefinitionRequest) (*DeleteAttributeDefinitionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAttributeDefinition not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductAttributes(context.Context, *SetProductAttributesRequest) (*SetProductAttributesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductAttributes not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductTranslations(context.Context, *SetProductTranslationsRequest) (*SetProductTranslationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductTranslations not implemented")
+}
+func (UnimplementedCatalogServiceServer) GetProductBySlug(context.Context, *GetProductBySlugRequest) (*GetProductBySlugResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProductBySlug not implemented")
+}
+func (UnimplementedCatalogServiceServer) ChangeProductSlug(context.Context, *ChangeProductSlugRequest) (*ChangeProductSlugResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeProductSlug not implemented")
+}
+func (UnimplementedCatalogServiceServer) SetProductBundle(context.Context, *SetProductBundleRequest) (*SetProductBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetProductBundle not implemented")
+}
 func (UnimplementedCatalogServiceServer) mustEmbedUnimplementedCatalogServiceServer() {}
 func (UnimplementedCatalogServiceServer) testEmbeddedByValue()                        {}
 
@@ -172,6 +545,377 @@ func _CatalogService_GetProducts_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CatalogService_UploadProductImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CatalogServiceServer).UploadProductImage(&grpc.GenericServerStream[UploadProductImageRequest, UploadProductImageResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CatalogService_UploadProductImageServer = grpc.ClientStreamingServer[UploadProductImageRequest, UploadProductImageResponse]
+
+func _CatalogService_UpdateProductImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProductImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).UpdateProductImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_UpdateProductImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).UpdateProductImage(ctx, req.(*UpdateProductImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_DeleteProductImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteProductImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).DeleteProductImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_DeleteProductImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).DeleteProductImage(ctx, req.(*DeleteProductImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_PostReview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostReviewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).PostReview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_PostReview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).PostReview(ctx, req.(*PostReviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetReviews_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReviewsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetReviews(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_GetReviews_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetReviews(ctx, req.(*GetReviewsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetRelatedProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRelatedProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetRelatedProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_GetRelatedProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetRelatedProducts(ctx, req.(*GetRelatedProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_DeleteProduct_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteProductRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).DeleteProduct(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_DeleteProduct_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).DeleteProduct(ctx, req.(*DeleteProductRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_WatchProducts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchProductsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServiceServer).WatchProducts(m, &grpc.GenericServerStream[WatchProductsRequest, ProductEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CatalogService_WatchProductsServer = grpc.ServerStreamingServer[ProductEvent]
+
+func _CatalogService_GetSynonyms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSynonymsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetSynonyms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_GetSynonyms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetSynonyms(ctx, req.(*GetSynonymsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_PutSynonym_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutSynonymRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).PutSynonym(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_PutSynonym_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).PutSynonym(ctx, req.(*PutSynonymRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_DeleteSynonym_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSynonymRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).DeleteSynonym(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_DeleteSynonym_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).DeleteSynonym(ctx, req.(*DeleteSynonymRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_SetProductStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductStatus(ctx, req.(*SetProductStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_ExportProducts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportProductsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServiceServer).ExportProducts(m, &grpc.GenericServerStream[ExportProductsRequest, Product]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CatalogService_ExportProductsServer = grpc.ServerStreamingServer[Product]
+
+func _CatalogService_ImportProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).ImportProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_ImportProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).ImportProducts(ctx, req.(*ImportProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetAttributeDefinitions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAttributeDefinitionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetAttributeDefinitions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_GetAttributeDefinitions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetAttributeDefinitions(ctx, req.(*GetAttributeDefinitionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_PutAttributeDefinition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutAttributeDefinitionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).PutAttributeDefinition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_PutAttributeDefinition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).PutAttributeDefinition(ctx, req.(*PutAttributeDefinitionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_DeleteAttributeDefinition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAttributeDefinitionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).DeleteAttributeDefinition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_DeleteAttributeDefinition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).DeleteAttributeDefinition(ctx, req.(*DeleteAttributeDefinitionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductAttributesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductAttributes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_SetProductAttributes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductAttributes(ctx, req.(*SetProductAttributesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductTranslations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductTranslationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductTranslations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_SetProductTranslations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductTranslations(ctx, req.(*SetProductTranslationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_GetProductBySlug_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProductBySlugRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).GetProductBySlug(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_GetProductBySlug_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).GetProductBySlug(ctx, req.(*GetProductBySlugRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_ChangeProductSlug_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeProductSlugRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).ChangeProductSlug(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_ChangeProductSlug_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).ChangeProductSlug(ctx, req.(*ChangeProductSlugRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CatalogService_SetProductBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetProductBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServiceServer).SetProductBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CatalogService_SetProductBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServiceServer).SetProductBundle(ctx, req.(*SetProductBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CatalogService_ServiceDesc is the grpc.ServiceDesc for CatalogService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,7 +935,99 @@ var CatalogService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProducts",
 			Handler:    _CatalogService_GetProducts_Handler,
 		},
+		{
+			MethodName: "UpdateProductImage",
+			Handler:    _CatalogService_UpdateProductImage_Handler,
+		},
+		{
+			MethodName: "DeleteProductImage",
+			Handler:    _CatalogService_DeleteProductImage_Handler,
+		},
+		{
+			MethodName: "PostReview",
+			Handler:    _CatalogService_PostReview_Handler,
+		},
+		{
+			MethodName: "GetReviews",
+			Handler:    _CatalogService_GetReviews_Handler,
+		},
+		{
+			MethodName: "GetRelatedProducts",
+			Handler:    _CatalogService_GetRelatedProducts_Handler,
+		},
+		{
+			MethodName: "DeleteProduct",
+			Handler:    _CatalogService_DeleteProduct_Handler,
+		},
+		{
+			MethodName: "GetSynonyms",
+			Handler:    _CatalogService_GetSynonyms_Handler,
+		},
+		{
+			MethodName: "PutSynonym",
+			Handler:    _CatalogService_PutSynonym_Handler,
+		},
+		{
+			MethodName: "DeleteSynonym",
+			Handler:    _CatalogService_DeleteSynonym_Handler,
+		},
+		{
+			MethodName: "SetProductStatus",
+			Handler:    _CatalogService_SetProductStatus_Handler,
+		},
+		{
+			MethodName: "ImportProducts",
+			Handler:    _CatalogService_ImportProducts_Handler,
+		},
+		{
+			MethodName: "GetAttributeDefinitions",
+			Handler:    _CatalogService_GetAttributeDefinitions_Handler,
+		},
+		{
+			MethodName: "PutAttributeDefinition",
+			Handler:    _CatalogService_PutAttributeDefinition_Handler,
+		},
+		{
+			MethodName: "DeleteAttributeDefinition",
+			Handler:    _CatalogService_DeleteAttributeDefinition_Handler,
+		},
+		{
+			MethodName: "SetProductAttributes",
+			Handler:    _CatalogService_SetProductAttributes_Handler,
+		},
+		{
+			MethodName: "SetProductTranslations",
+			Handler:    _CatalogService_SetProductTranslations_Handler,
+		},
+		{
+			MethodName: "GetProductBySlug",
+			Handler:    _CatalogService_GetProductBySlug_Handler,
+		},
+		{
+			MethodName: "ChangeProductSlug",
+			Handler:    _CatalogService_ChangeProductSlug_Handler,
+		},
+		{
+			MethodName: "SetProductBundle",
+			Handler:    _CatalogService_SetProductBundle_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadProductImage",
+			Handler:       _CatalogService_UploadProductImage_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchProducts",
+			Handler:       _CatalogService_WatchProducts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportProducts",
+			Handler:       _CatalogService_ExportProducts_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "catalog.proto",
 }
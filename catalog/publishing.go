@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+type ProductStatus string
+
+const (
+	StatusDraft     ProductStatus = "draft"
+	StatusPublished ProductStatus = "published"
+	StatusArchived  ProductStatus = "archived"
+)
+
+var (
+	ErrInvalidStatus   = errors.New("Invalid product status")
+	ErrInvalidSchedule = errors.New("Unpublish time must be after publish time")
+
+	errNotDue = errors.New("Schedule is not due")
+)
+
+func (s ProductStatus) valid() bool {
+	return s == StatusDraft || s == StatusPublished || s == StatusArchived
+}
+
+// publishedFilter restricts a filter to what the public may see.
+func publishedFilter(f ProductFilter) ProductFilter {
+	published := ProductFilter{Statuses: []ProductStatus{StatusPublished}}
+	if f.IsEmpty() {
+		return published
+	}
+	return ProductFilter{And: []ProductFilter{published, f}}
+}
+
+// publishedProducts keeps the products the public may see.
+func publishedProducts(products []Product) []Product {
+	res := []Product{}
+	for _, p := range products {
+		if p.Status == StatusPublished {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// SetProductStatus moves a product to status right away and replaces its
+// schedule. A nil time clears that side of the schedule.
+func (s *catalogService) SetProductStatus(ctx context.Context, id string, status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (*Product, error) {
+	if !status.valid() {
+		return nil, ErrInvalidStatus
+	}
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return nil, ErrInvalidSchedule
+	}
+
+	err := s.repository.UpdateProductStatus(ctx, id, func(ProductStatus, *time.Time, *time.Time) (ProductStatus, *time.Time, *time.Time, error) {
+		return status, publishAt, unpublishAt, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	return s.repository.GetProductByID(ctx, id)
+}
+
+// RunScheduler applies due publish and unpublish times every interval until
+// ctx is done.
+func (s *catalogService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.applySchedule(ctx, time.Now().UTC()); err != nil {
+			log.Println("Error applying publishing schedule: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *catalogService) applySchedule(ctx context.Context, now time.Time) error {
+	due, err := s.repository.ListScheduledProducts(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range due {
+		// The schedule is checked again on the product as it is written,
+		// it may have been changed or applied since it was listed.
+		var applied ProductStatus
+		err := s.repository.UpdateProductStatus(ctx, p.ID, func(status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (ProductStatus, *time.Time, *time.Time, error) {
+			status, publishAt, unpublishAt, ok := dueSchedule(now, status, publishAt, unpublishAt)
+			if !ok {
+				return "", nil, nil, errNotDue
+			}
+			applied = status
+			return status, publishAt, unpublishAt, nil
+		})
+		if err == errNotDue || err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Product %s is now %s", p.ID, applied)
+	}
+
+	if len(due) > 0 {
+		s.changes.notify()
+	}
+	return nil
+}
+
+// dueSchedule applies the publish and unpublish times at or before now. ok
+// is false when none of them is due.
+func dueSchedule(now time.Time, status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (ProductStatus, *time.Time, *time.Time, bool) {
+	ok := false
+	if publishAt != nil && !publishAt.After(now) {
+		status, publishAt, ok = StatusPublished, nil, true
+	}
+	if unpublishAt != nil && !unpublishAt.After(now) && status == StatusPublished {
+		status, unpublishAt, ok = StatusArchived, nil, true
+	}
+	return status, publishAt, unpublishAt, ok
+}
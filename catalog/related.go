@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"context"
+	"log"
+	"sort"
+)
+
+// Weights of the two signals behind related products. Both are normalised
+// to [0, 1] before being combined.
+const (
+	similarityWeight  = 0.5
+	coPurchaseWeight  = 0.5
+	maxRelatedResults = 50
+)
+
+// GetRelatedProducts ranks products that read like the given one together
+// with products that are often ordered with it. When the order service is
+// unavailable only the text similarity is used.
+func (s *catalogService) GetRelatedProducts(ctx context.Context, id string, limit uint64) ([]Product, error) {
+	if limit == 0 || limit > maxRelatedResults {
+		limit = 10
+	}
+
+	similar, err := s.repository.SimilarProducts(ctx, id, limit*2)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.orders.CoPurchasedProducts(ctx, id, limit*2)
+	if err != nil {
+		log.Println("Error getting co-purchased products: ", err)
+		counts = map[string]uint64{}
+	}
+
+	maxScore := 0.0
+	for _, h := range similar {
+		if h.Score > maxScore {
+			maxScore = h.Score
+		}
+	}
+	maxCount := uint64(0)
+	for _, c := range counts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+
+	scores := map[string]float64{}
+	products := map[string]Product{}
+	for _, h := range similar {
+		products[h.Product.ID] = h.Product
+		if maxScore > 0 {
+			scores[h.Product.ID] += similarityWeight * h.Score / maxScore
+		}
+	}
+	for productID, c := range counts {
+		scores[productID] += coPurchaseWeight * float64(c) / float64(maxCount)
+	}
+	delete(scores, id)
+
+	ids := []string{}
+	for productID := range scores {
+		ids = append(ids, productID)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if uint64(len(ids)) > limit {
+		ids = ids[:limit]
+	}
+
+	missing := []string{}
+	for _, productID := range ids {
+		if _, ok := products[productID]; !ok {
+			missing = append(missing, productID)
+		}
+	}
+	if len(missing) > 0 {
+		res, err := s.repository.ListProductWithIDs(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range res {
+			products[p.ID] = p
+		}
+	}
+
+	related := []Product{}
+	for _, productID := range ids {
+		if p, ok := products[productID]; ok && p.Status == StatusPublished {
+			related = append(related, p)
+		}
+	}
+
+	return related, nil
+}
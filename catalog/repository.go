@@ -4,22 +4,67 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
 
 	"gopkg.in/olivere/elastic.v6"
 )
 
 var (
-	ErrNotFound = errors.New("Entity not found")
+	ErrNotFound       = errors.New("Entity not found")
+	ErrIndexOutOfDate = errors.New("Product index is out of date, run the reindex command")
 )
 
+// maxUpdateAttempts bounds how often a conditional write is retried after
+// losing to a concurrent one.
+const maxUpdateAttempts = 5
+
+// maxChangeAttempts bounds how many sequence numbers a change tries before
+// giving up to concurrent writers.
+const maxChangeAttempts = 100
+
 type Repository interface {
 	Close()
 	PutProduct(ctx context.Context, p Product) error
+	DeleteProduct(ctx context.Context, id string) error
 	GetProductByID(ctx context.Context, id string) (*Product, error)
 	ListProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error)
 	ListProductWithIDs(ctx context.Context, ids []string) ([]Product, error)
-	SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error)
+	FilterProducts(ctx context.Context, filter ProductFilter, skip uint64, take uint64) ([]SearchHit, error)
+	SimilarProducts(ctx context.Context, id string, take uint64) ([]SearchHit, error)
+	Reindex(ctx context.Context) error
+	UpdateProductRating(ctx context.Context, id string, update func(rating Rating) (Rating, error)) error
+	PutReview(ctx context.Context, r Review) error
+	ListReviews(ctx context.Context, productID string, skip uint64, take uint64) ([]Review, uint64, error)
+	AggregateRating(ctx context.Context, productID string) (*Rating, error)
+	ListChanges(ctx context.Context, after uint64, take uint64) ([]ProductChange, error)
+	LastChangeSequence(ctx context.Context) (uint64, error)
+	HasChange(ctx context.Context, sequence uint64) (bool, error)
+	ProductChangeSequences(ctx context.Context, ids []string) (map[string]uint64, error)
+	SuggestQuery(ctx context.Context, query string) (string, error)
+	ListSynonyms(ctx context.Context) ([]Synonym, error)
+	PutSynonym(ctx context.Context, s Synonym) error
+	DeleteSynonym(ctx context.Context, id string) error
+	UpdateProductStatus(ctx context.Context, id string, update func(status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (ProductStatus, *time.Time, *time.Time, error)) error
+	ListScheduledProducts(ctx context.Context, now time.Time) ([]Product, error)
+	ScanProducts(ctx context.Context, after string, take uint64) ([]Product, error)
+	ListAttributeDefinitions(ctx context.Context) ([]AttributeDefinition, error)
+	GetAttributeDefinition(ctx context.Context, name string) (*AttributeDefinition, error)
+	PutAttributeDefinition(ctx context.Context, d AttributeDefinition) error
+	DeleteAttributeDefinition(ctx context.Context, name string) error
+	UpdateProductAttributes(ctx context.Context, id string, attributes map[string]interface{}) error
+	FacetCounts(ctx context.Context, filter ProductFilter, names []string, size int) ([]Facet, error)
+	UpdateProductTranslations(ctx context.Context, id string, translations map[string]Translation) error
+	CreateSlug(ctx context.Context, slug string, productID string) error
+	PutSlug(ctx context.Context, slug string, productID string) error
+	GetSlug(ctx context.Context, slug string) (string, error)
+	UpdateProductSlug(ctx context.Context, id string, slug string) error
+	UpdateProductBundle(ctx context.Context, id string, bundle *Bundle, price float64) error
+	UpdateProductImages(ctx context.Context, id string, update func(images []Image) ([]Image, error)) (*Product, error)
 }
 
 type elasticsearchRepository struct {
@@ -27,12 +72,79 @@ type elasticsearchRepository struct {
 }
 
 type ProductDocument struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	Slug         string                 `json:"slug,omitempty"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	Price        float64                `json:"price"`
+	Images       []Image                `json:"images"`
+	Rating       Rating                 `json:"rating"`
+	Status       ProductStatus          `json:"status,omitempty"`
+	PublishAt    *time.Time             `json:"publish_at"`
+	UnpublishAt  *time.Time             `json:"unpublish_at"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+	Bundle       *Bundle                `json:"bundle,omitempty"`
+	// ChangeSequence is the change log entry of the last write.
+	ChangeSequence uint64 `json:"change_sequence,omitempty"`
 }
 
+func newProductDocument(p Product) ProductDocument {
+	return ProductDocument{
+		Slug:         p.Slug,
+		Name:         p.Name,
+		Description:  p.Description,
+		Price:        p.Price,
+		Images:       p.Images,
+		Rating:       p.Rating,
+		Status:       p.Status,
+		PublishAt:    p.PublishAt,
+		UnpublishAt:  p.UnpublishAt,
+		Attributes:   p.Attributes,
+		Translations: p.Translations,
+		Bundle:       p.Bundle,
+	}
+}
+
+func (d ProductDocument) product(id string) Product {
+	return Product{
+		ID:           id,
+		Slug:         d.Slug,
+		Name:         d.Name,
+		Description:  d.Description,
+		Price:        d.Price,
+		Images:       d.Images,
+		Rating:       d.Rating,
+		Status:       d.status(),
+		PublishAt:    d.PublishAt,
+		UnpublishAt:  d.UnpublishAt,
+		Attributes:   d.Attributes,
+		Translations: d.Translations,
+		Bundle:       d.Bundle,
+	}
+}
+
+// status treats documents indexed before products had a status as
+// published, they were live when they were written.
+func (d ProductDocument) status() ProductStatus {
+	if d.Status == "" {
+		return StatusPublished
+	}
+	return d.Status
+}
+
+// NewElasticsearchRepository fails with ErrIndexOutOfDate until the product
+// index has the current mapping, writes of new fields would be rejected.
 func NewElasticsearchRepository(url string) (Repository, error) {
+	return newElasticsearchRepository(url, true)
+}
+
+// NewReindexRepository accepts an out of date product index, for the
+// reindex command that brings it up to date.
+func NewReindexRepository(url string) (Repository, error) {
+	return newElasticsearchRepository(url, false)
+}
+
+func newElasticsearchRepository(url string, requireCurrent bool) (Repository, error) {
 	client, err := elastic.NewClient(
 		elastic.SetURL(url),
 		elastic.SetSniff(false),
@@ -41,7 +153,27 @@ func NewElasticsearchRepository(url string) (Repository, error) {
 		return nil, err
 	}
 
-	return &elasticsearchRepository{client}, nil
+	r := &elasticsearchRepository{client}
+	// Side indices come first, building the product index reads synonyms.
+	sideIndices := map[string]string{
+		reviewIndex:    reviewMapping,
+		changeIndex:    changeMapping,
+		synonymIndex:   synonymMapping,
+		attributeIndex: attributeMapping,
+		slugIndex:      slugMapping,
+	}
+	for name, mapping := range sideIndices {
+		if err := r.ensureSideIndex(context.Background(), name, mapping); err != nil {
+			client.Stop()
+			return nil, err
+		}
+	}
+	if err := r.ensureIndex(context.Background(), requireCurrent); err != nil {
+		client.Stop()
+		return nil, err
+	}
+
+	return r, nil
 }
 
 func (r *elasticsearchRepository) Close() {
@@ -49,24 +181,27 @@ func (r *elasticsearchRepository) Close() {
 }
 
 func (r *elasticsearchRepository) PutProduct(ctx context.Context, p Product) error {
-	_, err := r.client.Index().
-		Index("catalog").
-		Type("product").
-		Id(p.ID).
-		BodyJson(ProductDocument{
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		}).
-		Do(ctx)
+	_, err := r.writeProduct(ctx, p.ID, func(*ProductDocument) (*ProductDocument, error) {
+		doc := newProductDocument(p)
+		return &doc, nil
+	})
+	return err
+}
 
+func (r *elasticsearchRepository) DeleteProduct(ctx context.Context, id string) error {
+	_, err := r.writeProduct(ctx, id, func(old *ProductDocument) (*ProductDocument, error) {
+		if old == nil {
+			return nil, ErrNotFound
+		}
+		return nil, nil
+	})
 	return err
 }
 
 func (r *elasticsearchRepository) GetProductByID(ctx context.Context, id string) (*Product, error) {
 	res, err := r.client.Get().
-		Index("catalog").
-		Type("product").
+		Index(indexAlias).
+		Type(productType).
 		Id(id).
 		Do(ctx)
 
@@ -83,18 +218,128 @@ func (r *elasticsearchRepository) GetProductByID(ctx context.Context, id string)
 		return nil, err
 	}
 
-	return &Product{
-		ID:          id,
-		Name:        doc.Name,
-		Description: doc.Description,
-		Price:       doc.Price,
-	}, nil
+	p := doc.product(id)
+	return &p, nil
+}
+
+// writeProduct changes a product and logs the change. update gets the
+// current document, nil when there is none, and returns the new one, nil to
+// delete the product. The change takes its sequence first and the product
+// is written with it only if nobody wrote it in between, otherwise update
+// runs again. So the sequences a product carries only grow, and a writer
+// that fails after logging leaves a change that never landed, which
+// watchers skip, instead of a write missing from the log.
+func (r *elasticsearchRepository) writeProduct(ctx context.Context, id string, update func(old *ProductDocument) (*ProductDocument, error)) (*ProductDocument, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := r.client.Get().
+			Index(indexAlias).
+			Type(productType).
+			Id(id).
+			Do(ctx)
+
+		if err != nil && !elastic.IsNotFound(err) {
+			return nil, err
+		}
+
+		var old *ProductDocument
+		if err == nil && res.Found {
+			if res.SeqNo == nil || res.PrimaryTerm == nil {
+				return nil, fmt.Errorf("product %s has no sequence number", id)
+			}
+			old = &ProductDocument{}
+			if err = json.Unmarshal(*res.Source, old); err != nil {
+				return nil, err
+			}
+		}
+
+		doc, err := update(old)
+		if err != nil {
+			return nil, err
+		}
+
+		change := ChangeUpdated
+		switch {
+		case doc == nil:
+			change = ChangeDeleted
+		case old == nil:
+			change = ChangeCreated
+		}
+		sequence, err := r.appendChange(ctx, change, id, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		// Watchers give up on a change that has not landed after
+		// changeWriteTimeout, half of it leaves room for the clocks.
+		writeCtx, cancel := context.WithTimeout(ctx, changeWriteTimeout/2)
+		if doc == nil {
+			_, err = r.client.Delete().
+				Index(indexAlias).
+				Type(productType).
+				Id(id).
+				IfSeqNo(*res.SeqNo).
+				IfPrimaryTerm(*res.PrimaryTerm).
+				Do(writeCtx)
+		} else {
+			doc.ChangeSequence = sequence
+			index := r.client.Index().
+				Index(indexAlias).
+				Type(productType).
+				Id(id).
+				BodyJson(doc)
+			if old == nil {
+				index = index.OpType("create")
+			} else {
+				index = index.IfSeqNo(*res.SeqNo).IfPrimaryTerm(*res.PrimaryTerm)
+			}
+			_, err = index.Do(writeCtx)
+		}
+		cancel()
+
+		lost := elastic.IsConflict(err) || (doc == nil && elastic.IsNotFound(err))
+		if lost && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+}
+
+// updateProduct changes an existing product through writeProduct.
+func (r *elasticsearchRepository) updateProduct(ctx context.Context, id string, update func(doc *ProductDocument) error) (*ProductDocument, error) {
+	return r.writeProduct(ctx, id, func(old *ProductDocument) (*ProductDocument, error) {
+		if old == nil {
+			return nil, ErrNotFound
+		}
+		if err := update(old); err != nil {
+			return nil, err
+		}
+		return old, nil
+	})
+}
+
+// UpdateProductImages replaces the images of a product with what update
+// makes of them. update runs again on the newer images when somebody
+// changed the product in between, see writeProduct.
+func (r *elasticsearchRepository) UpdateProductImages(ctx context.Context, id string, update func(images []Image) ([]Image, error)) (*Product, error) {
+	doc, err := r.updateProduct(ctx, id, func(doc *ProductDocument) (err error) {
+		doc.Images, err = update(doc.Images)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := doc.product(id)
+	return &p, nil
 }
 
 func (r *elasticsearchRepository) ListProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error) {
 	res, err := r.client.Search().
-		Index("catalog").
-		Type("product").
+		Index(indexAlias).
+		Type(productType).
 		Query(elastic.NewMatchAllQuery()).
 		From(int(skip)).
 		Size(int(take)).
@@ -112,30 +357,22 @@ func (r *elasticsearchRepository) ListProducts(ctx context.Context, skip uint64,
 		if err = json.Unmarshal(*hit.Source, &p); err != nil {
 			return nil, err
 		}
-		products = append(products, Product{
-			ID:          hit.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, p.product(hit.Id))
 	}
 
 	return products, err
 }
 
+// ListProductWithIDs returns the products that exist among ids, missing ones
+// are left out.
 func (r *elasticsearchRepository) ListProductWithIDs(ctx context.Context, ids []string) ([]Product, error) {
 	items := []*elastic.MultiGetItem{}
 
-	var version int64 = 6 // Or get the version from elsewhere
-	versionPtr := &version
-
 	for _, id := range ids {
 		items = append(items, elastic.NewMultiGetItem().
-			Index("catalog").
-			Type("product").
-			Id(id).
-			Version(*versionPtr).
-			VersionType("external"))
+			Index(indexAlias).
+			Type(productType).
+			Id(id))
 	}
 	res, err := r.client.Mget().
 		Add(items...).
@@ -149,34 +386,261 @@ func (r *elasticsearchRepository) ListProductWithIDs(ctx context.Context, ids []
 	products := []Product{}
 
 	for _, doc := range res.Docs {
+		if !doc.Found || doc.Source == nil {
+			continue
+		}
 		p := ProductDocument{}
 		if err = json.Unmarshal(*doc.Source, &p); err != nil {
 			return nil, err
 		}
-		products = append(products, Product{
-			ID:          doc.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, p.product(doc.Id))
 	}
 
 	return products, err
 }
 
-func (r *elasticsearchRepository) SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error) {
+func (r *elasticsearchRepository) FilterProducts(ctx context.Context, filter ProductFilter, skip uint64, take uint64) ([]SearchHit, error) {
+	query, err := filter.esQuery()
+	if err != nil {
+		return nil, err
+	}
+
 	res, err := r.client.Search().
-		Index("catalog").
-		Type("product").
-		Query(elastic.NewMultiMatchQuery(query)).
+		Index(indexAlias).
+		Type(productType).
+		Query(query).
+		Highlight(elastic.NewHighlight().
+			Fields(
+				elastic.NewHighlighterField("name").NumOfFragments(0),
+				elastic.NewHighlighterField("description"),
+				elastic.NewHighlighterField("translations.*.name").NumOfFragments(0),
+				elastic.NewHighlighterField("translations.*.description"),
+			).
+			PreTags("<em>").
+			PostTags("</em>")).
 		From(int(skip)).
 		Size(int(take)).
+		RestTotalHitsAsInt(true).
 		Do(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
+	hits := []SearchHit{}
+
+	for _, hit := range res.Hits.Hits {
+		p := ProductDocument{}
+		if err = json.Unmarshal(*hit.Source, &p); err != nil {
+			return nil, err
+		}
+		h := SearchHit{
+			Product:    p.product(hit.Id),
+			Highlights: localHighlights(hit.Highlight),
+		}
+		if hit.Score != nil {
+			h.Score = *hit.Score
+		}
+		hits = append(hits, h)
+	}
+
+	return hits, nil
+}
+
+// localHighlights files highlights of translated fields under the plain
+// field name. A query only searches one locale, so they win over the default
+// locale's.
+func localHighlights(hl elastic.SearchHitHighlight) map[string][]string {
+	highlights := map[string][]string{}
+	for field, fragments := range hl {
+		if !strings.HasPrefix(field, "translations.") {
+			highlights[field] = fragments
+		}
+	}
+	for field, fragments := range hl {
+		if strings.HasPrefix(field, "translations.") {
+			highlights[field[strings.LastIndex(field, ".")+1:]] = fragments
+		}
+	}
+	return highlights
+}
+
+func (r *elasticsearchRepository) SimilarProducts(ctx context.Context, id string, take uint64) ([]SearchHit, error) {
+	res, err := r.client.Search().
+		Index(indexAlias).
+		Type(productType).
+		Query(elastic.NewMoreLikeThisQuery().
+			Field("name", "description").
+			LikeItems(elastic.NewMoreLikeThisQueryItem().Index(indexAlias).Type(productType).Id(id)).
+			MinTermFreq(1).
+			MinDocFreq(1)).
+		Size(int(take)).
+		RestTotalHitsAsInt(true).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []SearchHit{}
+
+	for _, hit := range res.Hits.Hits {
+		p := ProductDocument{}
+		if err = json.Unmarshal(*hit.Source, &p); err != nil {
+			return nil, err
+		}
+		h := SearchHit{Product: p.product(hit.Id)}
+		if hit.Score != nil {
+			h.Score = *hit.Score
+		}
+		hits = append(hits, h)
+	}
+
+	return hits, nil
+}
+
+// UpdateProductRating replaces the rating of a product with what update
+// makes of it, running update again when the product changed in between.
+func (r *elasticsearchRepository) UpdateProductRating(ctx context.Context, id string, update func(rating Rating) (Rating, error)) error {
+	_, err := r.updateProduct(ctx, id, func(doc *ProductDocument) (err error) {
+		doc.Rating, err = update(doc.Rating)
+		return err
+	})
+	return err
+}
+
+func (r *elasticsearchRepository) PutReview(ctx context.Context, review Review) error {
+	_, err := r.client.Index().
+		Index(reviewIndex).
+		Type(reviewType).
+		Id(review.ID).
+		BodyJson(review).
+		Refresh("wait_for").
+		Do(ctx)
+
+	return err
+}
+
+func (r *elasticsearchRepository) ListReviews(ctx context.Context, productID string, skip uint64, take uint64) ([]Review, uint64, error) {
+	res, err := r.client.Search().
+		Index(reviewIndex).
+		Type(reviewType).
+		Query(elastic.NewTermQuery("product_id", productID)).
+		Sort("created_at", false).
+		From(int(skip)).
+		Size(int(take)).
+		RestTotalHitsAsInt(true).
+		Do(ctx)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reviews := []Review{}
+
+	for _, hit := range res.Hits.Hits {
+		review := Review{}
+		if err = json.Unmarshal(*hit.Source, &review); err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, uint64(res.Hits.TotalHits), nil
+}
+
+func (r *elasticsearchRepository) AggregateRating(ctx context.Context, productID string) (*Rating, error) {
+	res, err := r.client.Search().
+		Index(reviewIndex).
+		Type(reviewType).
+		Query(elastic.NewTermQuery("product_id", productID)).
+		Aggregation("average", elastic.NewAvgAggregation().Field("rating")).
+		Size(0).
+		RestTotalHitsAsInt(true).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rating := &Rating{Count: uint64(res.Hits.TotalHits)}
+	if avg, found := res.Aggregations.Avg("average"); found && avg.Value != nil {
+		rating.Average = *avg.Value
+	}
+
+	return rating, nil
+}
+
+// UpdateProductStatus replaces the status and schedule of a product with
+// what update makes of them, running update again when the product changed
+// in between.
+func (r *elasticsearchRepository) UpdateProductStatus(ctx context.Context, id string, update func(status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (ProductStatus, *time.Time, *time.Time, error)) error {
+	_, err := r.updateProduct(ctx, id, func(doc *ProductDocument) (err error) {
+		doc.Status, doc.PublishAt, doc.UnpublishAt, err = update(doc.status(), doc.PublishAt, doc.UnpublishAt)
+		return err
+	})
+	return err
+}
+
+// ListScheduledProducts returns the products with a publish or unpublish
+// time at or before now that has not been applied yet.
+func (r *elasticsearchRepository) ListScheduledProducts(ctx context.Context, now time.Time) ([]Product, error) {
+	published, err := ProductFilter{Statuses: []ProductStatus{StatusPublished}}.esQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Search().
+		Index(indexAlias).
+		Type(productType).
+		Query(elastic.NewBoolQuery().
+			Should(
+				elastic.NewBoolQuery().
+					Filter(elastic.NewRangeQuery("publish_at").Lte(now)).
+					MustNot(published),
+				elastic.NewBoolQuery().
+					Filter(elastic.NewRangeQuery("unpublish_at").Lte(now), published),
+			).
+			MinimumNumberShouldMatch(1)).
+		Size(1000).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	products := []Product{}
+
+	for _, hit := range res.Hits.Hits {
+		p := ProductDocument{}
+		if err = json.Unmarshal(*hit.Source, &p); err != nil {
+			return nil, err
+		}
+		products = append(products, p.product(hit.Id))
+	}
+
+	return products, nil
+}
+
+// ScanProducts pages through every product ordered by id, returning the
+// ones after the given id. Unlike ListProducts it is not limited by the
+// result window.
+func (r *elasticsearchRepository) ScanProducts(ctx context.Context, after string, take uint64) ([]Product, error) {
+	search := r.client.Search().
+		Index(indexAlias).
+		Type(productType).
+		Query(elastic.NewMatchAllQuery()).
+		Sort("_id", true).
+		Size(int(take))
+	if after != "" {
+		search = search.SearchAfter(after)
+	}
+
+	res, err := search.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	products := []Product{}
 
 	for _, hit := range res.Hits.Hits {
@@ -184,13 +648,490 @@ func (r *elasticsearchRepository) SearchProducts(ctx context.Context, query stri
 		if err = json.Unmarshal(*hit.Source, &p); err != nil {
 			return nil, err
 		}
-		products = append(products, Product{
-			ID:          hit.Id,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		products = append(products, p.product(hit.Id))
 	}
 
 	return products, nil
 }
+
+// UpdateProductAttributes replaces the attribute object as a whole.
+func (r *elasticsearchRepository) UpdateProductAttributes(ctx context.Context, id string, attributes map[string]interface{}) error {
+	_, err := r.updateProduct(ctx, id, func(doc *ProductDocument) error {
+		doc.Attributes = attributes
+		return nil
+	})
+	return err
+}
+
+func (r *elasticsearchRepository) FacetCounts(ctx context.Context, filter ProductFilter, names []string, size int) ([]Facet, error) {
+	query, err := filter.esQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	search := r.client.Search().
+		Index(indexAlias).
+		Type(productType).
+		Query(query).
+		Size(0)
+	for _, name := range names {
+		search = search.Aggregation(name, elastic.NewTermsAggregation().Field("attributes."+name).Size(size))
+	}
+
+	res, err := search.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	facets := []Facet{}
+	for _, name := range names {
+		facet := Facet{Name: name, Buckets: []FacetBucket{}}
+		if terms, found := res.Aggregations.Terms(name); found {
+			for _, b := range terms.Buckets {
+				value := fmt.Sprint(b.Key)
+				if b.KeyAsString != nil {
+					value = *b.KeyAsString
+				}
+				facet.Buckets = append(facet.Buckets, FacetBucket{Value: value, Count: uint64(b.DocCount)})
+			}
+		}
+		facets = append(facets, facet)
+	}
+
+	return facets, nil
+}
+
+// attributeDocument is a definition as stored. Deleted definitions stay as
+// tombstones, the type of their values is still mapped.
+type attributeDocument struct {
+	AttributeDefinition
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func (r *elasticsearchRepository) ListAttributeDefinitions(ctx context.Context) ([]AttributeDefinition, error) {
+	res, err := r.client.Search().
+		Index(attributeIndex).
+		Type(attributeType).
+		Query(elastic.NewBoolQuery().MustNot(elastic.NewTermQuery("deleted", true))).
+		Sort("name", true).
+		Size(10000).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defs := []AttributeDefinition{}
+
+	for _, hit := range res.Hits.Hits {
+		d := AttributeDefinition{}
+		if err = json.Unmarshal(*hit.Source, &d); err != nil {
+			return nil, err
+		}
+		defs = append(defs, d)
+	}
+
+	return defs, nil
+}
+
+// GetAttributeDefinition returns a definition even when it was deleted.
+func (r *elasticsearchRepository) GetAttributeDefinition(ctx context.Context, name string) (*AttributeDefinition, error) {
+	res, err := r.client.Get().
+		Index(attributeIndex).
+		Type(attributeType).
+		Id(name).
+		Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !res.Found {
+		return nil, ErrNotFound
+	}
+
+	doc := attributeDocument{}
+	if err = json.Unmarshal(*res.Source, &doc); err != nil {
+		return nil, err
+	}
+	return &doc.AttributeDefinition, nil
+}
+
+func (r *elasticsearchRepository) PutAttributeDefinition(ctx context.Context, d AttributeDefinition) error {
+	_, err := r.client.Index().
+		Index(attributeIndex).
+		Type(attributeType).
+		Id(d.Name).
+		BodyJson(attributeDocument{AttributeDefinition: d}).
+		Refresh("wait_for").
+		Do(ctx)
+
+	return err
+}
+
+// DeleteAttributeDefinition turns a definition into a tombstone.
+func (r *elasticsearchRepository) DeleteAttributeDefinition(ctx context.Context, name string) error {
+	res, err := r.client.Update().
+		Index(attributeIndex).
+		Type(attributeType).
+		Id(name).
+		Doc(map[string]interface{}{"deleted": true}).
+		Refresh("wait_for").
+		Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if res.Result == "noop" {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateProductTranslations replaces the translations as a whole.
+func (r *elasticsearchRepository) UpdateProductTranslations(ctx context.Context, id string, translations map[string]Translation) error {
+	_, err := r.updateProduct(ctx, id, func(doc *ProductDocument) error {
+		doc.Translations = translations
+		return nil
+	})
+	return err
+}
+
+// UpdateProductBundle replaces the bundle of a product together with its
+// price, a nil bundle makes it a simple product again.
+func (r *elasticsearchRepository) UpdateProductBundle(ctx context.Context, id string, bundle *Bundle, price float64) error {
+	_, err := r.updateProduct(ctx, id, func(doc *ProductDocument) error {
+		doc.Bundle = bundle
+		doc.Price = price
+		return nil
+	})
+	return err
+}
+
+type slugDocument struct {
+	Slug      string    `json:"slug"`
+	ProductID string    `json:"product_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateSlug reserves slug for a product, failing with ErrSlugTaken when any
+// product ever had it.
+func (r *elasticsearchRepository) CreateSlug(ctx context.Context, slug string, productID string) error {
+	_, err := r.client.Index().
+		Index(slugIndex).
+		Type(slugType).
+		Id(slug).
+		OpType("create").
+		BodyJson(slugDocument{Slug: slug, ProductID: productID, CreatedAt: time.Now().UTC()}).
+		Refresh("wait_for").
+		Do(ctx)
+
+	if elastic.IsConflict(err) {
+		return ErrSlugTaken
+	}
+	return err
+}
+
+// PutSlug points slug at a product whoever had it before.
+func (r *elasticsearchRepository) PutSlug(ctx context.Context, slug string, productID string) error {
+	_, err := r.client.Index().
+		Index(slugIndex).
+		Type(slugType).
+		Id(slug).
+		BodyJson(slugDocument{Slug: slug, ProductID: productID, CreatedAt: time.Now().UTC()}).
+		Refresh("wait_for").
+		Do(ctx)
+
+	return err
+}
+
+func (r *elasticsearchRepository) GetSlug(ctx context.Context, slug string) (string, error) {
+	res, err := r.client.Get().
+		Index(slugIndex).
+		Type(slugType).
+		Id(slug).
+		Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if !res.Found {
+		return "", ErrNotFound
+	}
+
+	doc := slugDocument{}
+	if err = json.Unmarshal(*res.Source, &doc); err != nil {
+		return "", err
+	}
+	return doc.ProductID, nil
+}
+
+func (r *elasticsearchRepository) UpdateProductSlug(ctx context.Context, id string, slug string) error {
+	_, err := r.updateProduct(ctx, id, func(doc *ProductDocument) error {
+		doc.Slug = slug
+		return nil
+	})
+	return err
+}
+
+type changeDocument struct {
+	Sequence  uint64           `json:"sequence"`
+	Type      ChangeType       `json:"type"`
+	ProductID string           `json:"product_id"`
+	Product   *ProductDocument `json:"product,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// appendChange records a product change under the next sequence number and
+// returns it. Every change is its own document whose id is its sequence
+// number, created only if that id is free. A writer that loses a number to
+// another one takes the next, so a number is only used once the one before
+// it exists and the log has no holes.
+func (r *elasticsearchRepository) appendChange(ctx context.Context, t ChangeType, productID string, p *ProductDocument) (uint64, error) {
+	last, err := r.LastChangeSequence(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	doc := changeDocument{
+		Type:      t,
+		ProductID: productID,
+		Product:   p,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	for doc.Sequence = last + 1; doc.Sequence <= last+maxChangeAttempts; doc.Sequence++ {
+		_, err = r.client.Index().
+			Index(changeIndex).
+			Type(changeType).
+			Id(strconv.FormatUint(doc.Sequence, 10)).
+			OpType("create").
+			BodyJson(doc).
+			Refresh("wait_for").
+			Do(ctx)
+
+		if !elastic.IsConflict(err) {
+			return doc.Sequence, err
+		}
+	}
+	return 0, fmt.Errorf("no free change sequence in the %d after %d", maxChangeAttempts, last)
+}
+
+// ProductChangeSequences returns the sequence of the last change written
+// to each of the products that exist, zero for products last written
+// before they carried it.
+func (r *elasticsearchRepository) ProductChangeSequences(ctx context.Context, ids []string) (map[string]uint64, error) {
+	sequences := map[string]uint64{}
+	if len(ids) == 0 {
+		return sequences, nil
+	}
+
+	items := []*elastic.MultiGetItem{}
+	for _, id := range ids {
+		items = append(items, elastic.NewMultiGetItem().
+			Index(indexAlias).
+			Type(productType).
+			Id(id).
+			FetchSource(elastic.NewFetchSourceContext(true).Include("change_sequence")))
+	}
+	res, err := r.client.Mget().
+		Add(items...).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range res.Docs {
+		if !doc.Found || doc.Source == nil {
+			continue
+		}
+		p := ProductDocument{}
+		if err = json.Unmarshal(*doc.Source, &p); err != nil {
+			return nil, err
+		}
+		sequences[doc.Id] = p.ChangeSequence
+	}
+
+	return sequences, nil
+}
+
+func (r *elasticsearchRepository) ListChanges(ctx context.Context, after uint64, take uint64) ([]ProductChange, error) {
+	res, err := r.client.Search().
+		Index(changeIndex).
+		Type(changeType).
+		Query(elastic.NewRangeQuery("sequence").Gt(after)).
+		Sort("sequence", true).
+		Size(int(take)).
+		RestTotalHitsAsInt(true).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ProductChange{}
+
+	for _, hit := range res.Hits.Hits {
+		doc := changeDocument{}
+		if err = json.Unmarshal(*hit.Source, &doc); err != nil {
+			return nil, err
+		}
+		c := ProductChange{
+			Sequence:  doc.Sequence,
+			Type:      doc.Type,
+			ProductID: doc.ProductID,
+			CreatedAt: doc.CreatedAt,
+		}
+		if doc.Product != nil {
+			p := doc.Product.product(doc.ProductID)
+			c.Product = &p
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+func (r *elasticsearchRepository) LastChangeSequence(ctx context.Context) (uint64, error) {
+	res, err := r.client.Search().
+		Index(changeIndex).
+		Type(changeType).
+		Sort("sequence", false).
+		Size(1).
+		RestTotalHitsAsInt(true).
+		Do(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Hits.Hits) == 0 {
+		return 0, nil
+	}
+
+	doc := changeDocument{}
+	if err = json.Unmarshal(*res.Hits.Hits[0].Source, &doc); err != nil {
+		return 0, err
+	}
+	return doc.Sequence, nil
+}
+
+// HasChange looks a change up by its id, which unlike a search sees it as
+// soon as it was written.
+func (r *elasticsearchRepository) HasChange(ctx context.Context, sequence uint64) (bool, error) {
+	res, err := r.client.Get().
+		Index(changeIndex).
+		Type(changeType).
+		Id(strconv.FormatUint(sequence, 10)).
+		FetchSource(false).
+		Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return res.Found, nil
+}
+
+// SuggestQuery rewrites the words of query that do not occur in any product
+// to the closest ones that do. It returns an empty string when nothing
+// changed.
+func (r *elasticsearchRepository) SuggestQuery(ctx context.Context, query string) (string, error) {
+	res, err := r.client.Search().
+		Index(indexAlias).
+		Type(productType).
+		Suggester(elastic.NewTermSuggester("did_you_mean").
+			Text(query).
+			Field("suggest").
+			SuggestMode("missing").
+			Size(1)).
+		Size(0).
+		Do(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Offsets count UTF-16 code units, replace from the back so earlier
+	// offsets stay valid.
+	text := utf16.Encode([]rune(query))
+	entries := res.Suggest["did_you_mean"]
+	changed := false
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if len(e.Options) == 0 || e.Offset+e.Length > len(text) {
+			continue
+		}
+		replaced := append([]uint16{}, text[:e.Offset]...)
+		replaced = append(replaced, utf16.Encode([]rune(e.Options[0].Text))...)
+		text = append(replaced, text[e.Offset+e.Length:]...)
+		changed = true
+	}
+
+	if !changed {
+		return "", nil
+	}
+	return string(utf16.Decode(text)), nil
+}
+
+func (r *elasticsearchRepository) ListSynonyms(ctx context.Context) ([]Synonym, error) {
+	res, err := r.client.Search().
+		Index(synonymIndex).
+		Type(synonymType).
+		Query(elastic.NewMatchAllQuery()).
+		Sort("id", true).
+		Size(10000).
+		Do(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	synonyms := []Synonym{}
+
+	for _, hit := range res.Hits.Hits {
+		s := Synonym{}
+		if err = json.Unmarshal(*hit.Source, &s); err != nil {
+			return nil, err
+		}
+		synonyms = append(synonyms, s)
+	}
+
+	return synonyms, nil
+}
+
+func (r *elasticsearchRepository) PutSynonym(ctx context.Context, s Synonym) error {
+	_, err := r.client.Index().
+		Index(synonymIndex).
+		Type(synonymType).
+		Id(s.ID).
+		BodyJson(s).
+		Refresh("wait_for").
+		Do(ctx)
+
+	return err
+}
+
+func (r *elasticsearchRepository) DeleteSynonym(ctx context.Context, id string) error {
+	_, err := r.client.Delete().
+		Index(synonymIndex).
+		Type(synonymType).
+		Id(id).
+		Refresh("wait_for").
+		Do(ctx)
+
+	if elastic.IsNotFound(err) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRating = errors.New("Rating must be between 1 and 5")
+	ErrNotPurchased  = errors.New("Product was not purchased by account")
+)
+
+type Review struct {
+	ID        string    `json:"id"`
+	ProductID string    `json:"product_id"`
+	AccountID string    `json:"account_id"`
+	Rating    int       `json:"rating"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Rating is the aggregate of all reviews of a product. It is stored on the
+// product document so products can be sorted by it.
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   uint64  `json:"count"`
+}
+
+// OrderHistory answers the questions the catalog has about past orders. The
+// order client implements it.
+type OrderHistory interface {
+	HasPurchased(ctx context.Context, accountID string, productID string) (bool, error)
+	CoPurchasedProducts(ctx context.Context, productID string, limit uint64) (map[string]uint64, error)
+}
+
+func reviewID(productID string, accountID string) string {
+	return productID + ":" + accountID
+}
+
+// PostReview stores the review of an account for a product, replacing any
+// earlier review by the same account, and refreshes the product rating.
+func (s *catalogService) PostReview(ctx context.Context, productID string, accountID string, rating int, body string) (*Review, error) {
+	if rating < 1 || rating > 5 {
+		return nil, ErrInvalidRating
+	}
+
+	if _, err := s.repository.GetProductByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	purchased, err := s.orders.HasPurchased(ctx, accountID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !purchased {
+		return nil, ErrNotPurchased
+	}
+
+	r := &Review{
+		ID:        reviewID(productID, accountID),
+		ProductID: productID,
+		AccountID: accountID,
+		Rating:    rating,
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repository.PutReview(ctx, *r); err != nil {
+		return nil, err
+	}
+
+	// The aggregate is taken again whenever another write wins the race
+	// to the product, a rating counting fewer reviews never lands last.
+	err = s.repository.UpdateProductRating(ctx, productID, func(Rating) (Rating, error) {
+		agg, err := s.repository.AggregateRating(ctx, productID)
+		if err != nil {
+			return Rating{}, err
+		}
+		return *agg, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	return r, nil
+}
+
+func (s *catalogService) GetReviews(ctx context.Context, productID string, skip uint64, take uint64) ([]Review, uint64, error) {
+	if skip > 100 || (take == 0 && skip == 0) {
+		take = 100
+	}
+	return s.repository.ListReviews(ctx, productID, skip, take)
+}
@@ -4,9 +4,11 @@ package catalog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"time"
 
 	"github.com/timothydzokoto/grpc_graphql_microservice/catalog/pb"
 	"google.golang.org/grpc"
@@ -39,39 +41,60 @@ func (s *grpcServer) PostProduct(ctx context.Context, req *pb.PostProductRequest
 		return nil, err
 	}
 
-	return &pb.PostProductResponse{Product: &pb.Product{
-		Id:          p.ID,
-		Name:        p.Name,
-		Description: p.Description,
-		Price:       p.Price,
-	}}, nil
+	return &pb.PostProductResponse{Product: productToProto(p)}, nil
 }
 
 func (s *grpcServer) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.GetProductResponse, error) {
 	p, err := s.service.GetProduct(ctx, req.Id)
+	if err == nil && !req.IncludeUnpublished && p.Status != StatusPublished {
+		err = ErrNotFound
+	}
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	return &pb.GetProductResponse{Product: &pb.Product{
-		Id:          p.ID,
-		Name:        p.Name,
-		Description: p.Description,
-		Price:       p.Price,
-	}}, nil
+	localized := p.Localized(req.Locale)
+	return &pb.GetProductResponse{Product: productToProto(&localized)}, nil
+}
+
+func (s *grpcServer) GetProductBySlug(ctx context.Context, req *pb.GetProductBySlugRequest) (*pb.GetProductBySlugResponse, error) {
+	p, moved, err := s.service.GetProductBySlug(ctx, req.Slug)
+	if err == nil && !req.IncludeUnpublished && p.Status != StatusPublished {
+		err = ErrNotFound
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	localized := p.Localized(req.Locale)
+	return &pb.GetProductBySlugResponse{Product: productToProto(&localized), Moved: moved}, nil
+}
+
+func (s *grpcServer) ChangeProductSlug(ctx context.Context, req *pb.ChangeProductSlugRequest) (*pb.ChangeProductSlugResponse, error) {
+	p, err := s.service.ChangeProductSlug(ctx, req.Id, req.Slug)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.ChangeProductSlugResponse{Product: productToProto(p)}, nil
 }
 
 func (s *grpcServer) GetProducts(ctx context.Context, req *pb.GetProductsRequest) (*pb.GetProductsResponse, error) {
+	if req.Query != "" || req.Filter != nil || (req.IncludeUnpublished && len(req.Ids) == 0) || len(req.Facets) > 0 || (len(req.Ids) > 0 && (req.Skip > 0 || req.Take > 0)) {
+		return s.filterProducts(ctx, req)
+	}
+
 	var res []Product
 	var err error
 
-	if req.Query != "" {
-		res, err = s.service.SearchProducts(ctx, req.Query, req.Skip, req.Take)
-
-	} else if len(req.Ids) > 0 {
+	if len(req.Ids) > 0 {
 		res, err = s.service.GetProductsWithIDs(ctx, req.Ids)
-
+		if !req.IncludeUnpublished {
+			res = publishedProducts(res)
+		}
 	} else {
 		res, err = s.service.GetProducts(ctx, req.Skip, req.Take)
 
@@ -83,12 +106,536 @@ func (s *grpcServer) GetProducts(ctx context.Context, req *pb.GetProductsRequest
 
 	products := []*pb.Product{}
 	for _, p := range res {
-		products = append(products, &pb.Product{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-		})
+		localized := p.Localized(req.Locale)
+		products = append(products, productToProto(&localized))
 	}
 	return &pb.GetProductsResponse{Products: products}, nil
 }
+
+func (s *grpcServer) filterProducts(ctx context.Context, req *pb.GetProductsRequest) (*pb.GetProductsResponse, error) {
+	filter := ProductFilter{}
+	if len(req.Ids) > 0 {
+		filter.And = append(filter.And, ProductFilter{IDs: req.Ids})
+	}
+	if req.Query != "" {
+		filter.And = append(filter.And, ProductFilter{Query: req.Query})
+	}
+	if req.Filter != nil {
+		f, err := filterFromProto(req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		filter.And = append(filter.And, f)
+	}
+	if !req.IncludeUnpublished {
+		filter = publishedFilter(filter)
+	}
+	filter = filter.withLocale(req.Locale)
+
+	res, err := s.service.FilterProducts(ctx, filter, req.Skip, req.Take)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	products := []*pb.Product{}
+	hits := []*pb.SearchHit{}
+	for _, h := range res {
+		localized := h.Product.Localized(req.Locale)
+		product := productToProto(&localized)
+		highlights := []*pb.SearchHit_Highlight{}
+		for _, field := range []string{"name", "description"} {
+			if fragments, ok := h.Highlights[field]; ok {
+				highlights = append(highlights, &pb.SearchHit_Highlight{Field: field, Fragments: fragments})
+			}
+		}
+
+		products = append(products, product)
+		hits = append(hits, &pb.SearchHit{
+			Product:    product,
+			Score:      h.Score,
+			Highlights: highlights,
+		})
+	}
+
+	suggestion := ""
+	if len(res) == 0 && req.Query != "" {
+		suggestion, err = s.service.SuggestQuery(ctx, req.Query)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+	facets := []*pb.Facet{}
+	if len(req.Facets) > 0 {
+		res, err := s.service.GetFacets(ctx, filter, req.Facets)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		for _, f := range res {
+			buckets := []*pb.Facet_Bucket{}
+			for _, b := range f.Buckets {
+				buckets = append(buckets, &pb.Facet_Bucket{Value: b.Value, Count: b.Count})
+			}
+			facets = append(facets, &pb.Facet{Name: f.Name, Buckets: buckets})
+		}
+	}
+
+	return &pb.GetProductsResponse{Products: products, Hits: hits, Suggestion: suggestion, Facets: facets}, nil
+}
+
+func (s *grpcServer) UploadProductImage(stream pb.CatalogService_UploadProductImageServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	meta := req.GetMetadata()
+	if meta == nil {
+		return errors.New("upload must start with metadata")
+	}
+
+	image, err := s.service.AddProductImage(stream.Context(), meta.ProductId, meta.AltText, int(meta.Position), &uploadReader{stream: stream})
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return stream.SendAndClose(&pb.UploadProductImageResponse{Image: imageToProto(*image)})
+}
+
+func (s *grpcServer) UpdateProductImage(ctx context.Context, req *pb.UpdateProductImageRequest) (*pb.UpdateProductImageResponse, error) {
+	var position *int
+	if req.Position != nil {
+		pos := int(*req.Position)
+		position = &pos
+	}
+
+	p, err := s.service.UpdateProductImage(ctx, req.ProductId, req.ImageId, req.AltText, position)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.UpdateProductImageResponse{Product: productToProto(p)}, nil
+}
+
+func (s *grpcServer) DeleteProductImage(ctx context.Context, req *pb.DeleteProductImageRequest) (*pb.DeleteProductImageResponse, error) {
+	p, err := s.service.DeleteProductImage(ctx, req.ProductId, req.ImageId)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.DeleteProductImageResponse{Product: productToProto(p)}, nil
+}
+
+func (s *grpcServer) PostReview(ctx context.Context, req *pb.PostReviewRequest) (*pb.PostReviewResponse, error) {
+	r, err := s.service.PostReview(ctx, req.ProductId, req.AccountId, int(req.Rating), req.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	review, err := reviewToProto(*r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.PostReviewResponse{Review: review}, nil
+}
+
+func (s *grpcServer) GetReviews(ctx context.Context, req *pb.GetReviewsRequest) (*pb.GetReviewsResponse, error) {
+	res, total, err := s.service.GetReviews(ctx, req.ProductId, req.Skip, req.Take)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	reviews := []*pb.Review{}
+	for _, r := range res {
+		review, err := reviewToProto(r)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return &pb.GetReviewsResponse{Reviews: reviews, Total: total}, nil
+}
+
+func (s *grpcServer) GetRelatedProducts(ctx context.Context, req *pb.GetRelatedProductsRequest) (*pb.GetRelatedProductsResponse, error) {
+	res, err := s.service.GetRelatedProducts(ctx, req.Id, req.Limit)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	products := []*pb.Product{}
+	for _, p := range res {
+		products = append(products, productToProto(&p))
+	}
+	return &pb.GetRelatedProductsResponse{Products: products}, nil
+}
+
+func (s *grpcServer) DeleteProduct(ctx context.Context, req *pb.DeleteProductRequest) (*pb.DeleteProductResponse, error) {
+	if err := s.service.DeleteProduct(ctx, req.Id); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.DeleteProductResponse{}, nil
+}
+
+func (s *grpcServer) WatchProducts(req *pb.WatchProductsRequest, stream pb.CatalogService_WatchProductsServer) error {
+	return s.service.WatchProducts(stream.Context(), req.Token, func(c ProductChange) error {
+		event, err := changeToProto(c)
+		if err != nil {
+			return err
+		}
+		return stream.Send(event)
+	})
+}
+
+func (s *grpcServer) GetSynonyms(ctx context.Context, req *pb.GetSynonymsRequest) (*pb.GetSynonymsResponse, error) {
+	res, err := s.service.GetSynonyms(ctx)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	synonyms := []*pb.Synonym{}
+	for _, syn := range res {
+		synonyms = append(synonyms, &pb.Synonym{Id: syn.ID, Terms: syn.Terms})
+	}
+	return &pb.GetSynonymsResponse{Synonyms: synonyms}, nil
+}
+
+func (s *grpcServer) PutSynonym(ctx context.Context, req *pb.PutSynonymRequest) (*pb.PutSynonymResponse, error) {
+	syn, err := s.service.PutSynonym(ctx, req.Id, req.Terms)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.PutSynonymResponse{Synonym: &pb.Synonym{Id: syn.ID, Terms: syn.Terms}}, nil
+}
+
+func (s *grpcServer) DeleteSynonym(ctx context.Context, req *pb.DeleteSynonymRequest) (*pb.DeleteSynonymResponse, error) {
+	if err := s.service.DeleteSynonym(ctx, req.Id); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.DeleteSynonymResponse{}, nil
+}
+
+func (s *grpcServer) SetProductStatus(ctx context.Context, req *pb.SetProductStatusRequest) (*pb.SetProductStatusResponse, error) {
+	publishAt, err := optionalTimeFromProto(req.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+	unpublishAt, err := optionalTimeFromProto(req.UnpublishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.service.SetProductStatus(ctx, req.Id, ProductStatus(req.Status), publishAt, unpublishAt)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.SetProductStatusResponse{Product: productToProto(p)}, nil
+}
+
+func (s *grpcServer) ExportProducts(req *pb.ExportProductsRequest, stream pb.CatalogService_ExportProductsServer) error {
+	return s.service.ExportProducts(stream.Context(), req.After, func(p Product) error {
+		return stream.Send(productToProto(&p))
+	})
+}
+
+func (s *grpcServer) ImportProducts(ctx context.Context, req *pb.ImportProductsRequest) (*pb.ImportProductsResponse, error) {
+	products := []Product{}
+	for _, p := range req.Products {
+		products = append(products, *productFromProto(p))
+	}
+
+	if err := s.service.ImportProducts(ctx, products); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.ImportProductsResponse{}, nil
+}
+
+func (s *grpcServer) GetAttributeDefinitions(ctx context.Context, req *pb.GetAttributeDefinitionsRequest) (*pb.GetAttributeDefinitionsResponse, error) {
+	res, err := s.service.GetAttributeDefinitions(ctx)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	definitions := []*pb.AttributeDefinition{}
+	for _, d := range res {
+		definitions = append(definitions, definitionToProto(d))
+	}
+	return &pb.GetAttributeDefinitionsResponse{Definitions: definitions}, nil
+}
+
+func (s *grpcServer) PutAttributeDefinition(ctx context.Context, req *pb.PutAttributeDefinitionRequest) (*pb.PutAttributeDefinitionResponse, error) {
+	d, err := s.service.PutAttributeDefinition(ctx, definitionFromProto(req.Definition))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.PutAttributeDefinitionResponse{Definition: definitionToProto(*d)}, nil
+}
+
+func (s *grpcServer) DeleteAttributeDefinition(ctx context.Context, req *pb.DeleteAttributeDefinitionRequest) (*pb.DeleteAttributeDefinitionResponse, error) {
+	if err := s.service.DeleteAttributeDefinition(ctx, req.Name); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.DeleteAttributeDefinitionResponse{}, nil
+}
+
+func (s *grpcServer) SetProductAttributes(ctx context.Context, req *pb.SetProductAttributesRequest) (*pb.SetProductAttributesResponse, error) {
+	p, err := s.service.SetProductAttributes(ctx, req.Id, attributesFromProto(req.Attributes))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.SetProductAttributesResponse{Product: productToProto(p)}, nil
+}
+
+func (s *grpcServer) SetProductTranslations(ctx context.Context, req *pb.SetProductTranslationsRequest) (*pb.SetProductTranslationsResponse, error) {
+	p, err := s.service.SetProductTranslations(ctx, req.Id, translationsFromProto(req.Translations))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.SetProductTranslationsResponse{Product: productToProto(p)}, nil
+}
+
+func (s *grpcServer) SetProductBundle(ctx context.Context, req *pb.SetProductBundleRequest) (*pb.SetProductBundleResponse, error) {
+	p, err := s.service.SetProductBundle(ctx, req.Id, bundleFromProto(req.Bundle))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &pb.SetProductBundleResponse{Product: productToProto(p)}, nil
+}
+
+// uploadReader exposes the chunks of an upload stream as an io.Reader.
+type uploadReader struct {
+	stream pb.CatalogService_UploadProductImageServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if req.GetMetadata() != nil {
+			return 0, errors.New("unexpected metadata in upload")
+		}
+		r.buf = req.GetChunk()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func productToProto(p *Product) *pb.Product {
+	images := []*pb.ProductImage{}
+	for _, i := range p.Images {
+		images = append(images, imageToProto(i))
+	}
+
+	return &pb.Product{
+		Id:          p.ID,
+		Slug:        p.Slug,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Images:      images,
+		Rating: &pb.Rating{
+			Average: p.Rating.Average,
+			Count:   p.Rating.Count,
+		},
+		Status:       string(p.Status),
+		PublishAt:    optionalTimeToProto(p.PublishAt),
+		UnpublishAt:  optionalTimeToProto(p.UnpublishAt),
+		Attributes:   attributesToProto(p.Attributes),
+		Translations: translationsToProto(p.Translations),
+		Bundle:       bundleToProto(p.Bundle),
+	}
+}
+
+func bundleToProto(b *Bundle) *pb.Bundle {
+	if b == nil {
+		return nil
+	}
+
+	components := []*pb.Bundle_Component{}
+	for _, c := range b.Components {
+		components = append(components, &pb.Bundle_Component{ProductId: c.ProductID, Quantity: c.Quantity})
+	}
+	return &pb.Bundle{Components: components, Pricing: string(b.Pricing), Discount: b.Discount}
+}
+
+func translationsToProto(translations map[string]Translation) map[string]*pb.Translation {
+	res := map[string]*pb.Translation{}
+	for locale, t := range translations {
+		res[locale] = &pb.Translation{Name: t.Name, Description: t.Description}
+	}
+	return res
+}
+
+func attributesToProto(attributes map[string]interface{}) map[string]*pb.AttributeValue {
+	values := map[string]*pb.AttributeValue{}
+	for name, v := range attributes {
+		switch v := v.(type) {
+		case string:
+			values[name] = &pb.AttributeValue{Value: &pb.AttributeValue_StringValue{StringValue: v}}
+		case float64:
+			values[name] = &pb.AttributeValue{Value: &pb.AttributeValue_NumberValue{NumberValue: v}}
+		case bool:
+			values[name] = &pb.AttributeValue{Value: &pb.AttributeValue_BoolValue{BoolValue: v}}
+		}
+	}
+	return values
+}
+
+func definitionToProto(d AttributeDefinition) *pb.AttributeDefinition {
+	return &pb.AttributeDefinition{Name: d.Name, Type: string(d.Type), Values: d.Values}
+}
+
+// optionalTimeToProto leaves nil times empty. UTC times always marshal, so
+// the error can be dropped.
+func optionalTimeToProto(t *time.Time) []byte {
+	if t == nil {
+		return nil
+	}
+	b, _ := t.UTC().MarshalBinary()
+	return b
+}
+
+func imageToProto(i Image) *pb.ProductImage {
+	thumbnails := []*pb.Thumbnail{}
+	for _, t := range i.Thumbnails {
+		thumbnails = append(thumbnails, &pb.Thumbnail{
+			Size:   t.Size,
+			Url:    t.URL,
+			Width:  int32(t.Width),
+			Height: int32(t.Height),
+		})
+	}
+
+	return &pb.ProductImage{
+		Id:          i.ID,
+		Url:         i.URL,
+		AltText:     i.AltText,
+		Position:    int32(i.Position),
+		ContentType: i.ContentType,
+		Width:       int32(i.Width),
+		Height:      int32(i.Height),
+		Thumbnails:  thumbnails,
+	}
+}
+
+func reviewToProto(r Review) (*pb.Review, error) {
+	createdAt, err := r.CreatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Review{
+		Id:        r.ID,
+		ProductId: r.ProductID,
+		AccountId: r.AccountID,
+		Rating:    int32(r.Rating),
+		Body:      r.Body,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+func filterFromProto(f *pb.ProductFilter) (ProductFilter, error) {
+	switch e := f.Expr.(type) {
+	case *pb.ProductFilter_And:
+		and, err := filtersFromProto(e.And.Filters)
+		return ProductFilter{And: and}, err
+	case *pb.ProductFilter_Or:
+		or, err := filtersFromProto(e.Or.Filters)
+		return ProductFilter{Or: or}, err
+	case *pb.ProductFilter_Ids:
+		return ProductFilter{IDs: append([]string{}, e.Ids.Ids...)}, nil
+	case *pb.ProductFilter_Query:
+		return ProductFilter{Query: e.Query}, nil
+	case *pb.ProductFilter_Price:
+		return ProductFilter{Price: &PriceRange{Min: e.Price.Min, Max: e.Price.Max}}, nil
+	case *pb.ProductFilter_Equals:
+		return ProductFilter{Equals: &FieldEquals{Field: e.Equals.Field, Value: e.Equals.Value}}, nil
+	case *pb.ProductFilter_Statuses_:
+		statuses := []ProductStatus{}
+		for _, status := range e.Statuses.Statuses {
+			statuses = append(statuses, ProductStatus(status))
+		}
+		return ProductFilter{Statuses: statuses}, nil
+	case *pb.ProductFilter_Attribute:
+		return ProductFilter{Attribute: &AttributeCondition{
+			Name:   e.Attribute.Name,
+			Values: e.Attribute.Values,
+			Min:    e.Attribute.Min,
+			Max:    e.Attribute.Max,
+		}}, nil
+	}
+	return ProductFilter{}, ErrInvalidFilter
+}
+
+func filtersFromProto(fs []*pb.ProductFilter) ([]ProductFilter, error) {
+	filters := []ProductFilter{}
+	for _, f := range fs {
+		filter, err := filterFromProto(f)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+var changeTypes = map[ChangeType]pb.ProductEvent_Type{
+	ChangeCreated: pb.ProductEvent_CREATED,
+	ChangeUpdated: pb.ProductEvent_UPDATED,
+	ChangeDeleted: pb.ProductEvent_DELETED,
+}
+
+func changeToProto(c ProductChange) (*pb.ProductEvent, error) {
+	createdAt, err := c.CreatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	event := &pb.ProductEvent{
+		Token:     c.Token(),
+		Type:      changeTypes[c.Type],
+		ProductId: c.ProductID,
+		CreatedAt: createdAt,
+	}
+	if c.Product != nil {
+		event.Product = productToProto(c.Product)
+	}
+
+	return event, nil
+}
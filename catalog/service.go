@@ -1,7 +1,12 @@
 package catalog
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/segmentio/ksuid"
 )
@@ -11,22 +16,75 @@ type Service interface {
 	GetProduct(ctx context.Context, id string) (*Product, error)
 	GetProducts(ctx context.Context, skip uint64, take uint64) ([]Product, error)
 	GetProductsWithIDs(ctx context.Context, ids []string) ([]Product, error)
-	SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error)
+	SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]SearchHit, error)
+	FilterProducts(ctx context.Context, filter ProductFilter, skip uint64, take uint64) ([]SearchHit, error)
+	AddProductImage(ctx context.Context, productID string, altText string, position int, r io.Reader) (*Image, error)
+	UpdateProductImage(ctx context.Context, productID string, imageID string, altText *string, position *int) (*Product, error)
+	DeleteProductImage(ctx context.Context, productID string, imageID string) (*Product, error)
+	PostReview(ctx context.Context, productID string, accountID string, rating int, body string) (*Review, error)
+	GetReviews(ctx context.Context, productID string, skip uint64, take uint64) ([]Review, uint64, error)
+	GetRelatedProducts(ctx context.Context, id string, limit uint64) ([]Product, error)
+	DeleteProduct(ctx context.Context, id string) error
+	WatchProducts(ctx context.Context, token string, send func(ProductChange) error) error
+	SuggestQuery(ctx context.Context, query string) (string, error)
+	GetSynonyms(ctx context.Context) ([]Synonym, error)
+	PutSynonym(ctx context.Context, id string, terms []string) (*Synonym, error)
+	DeleteSynonym(ctx context.Context, id string) error
+	SetProductStatus(ctx context.Context, id string, status ProductStatus, publishAt *time.Time, unpublishAt *time.Time) (*Product, error)
+	RunScheduler(ctx context.Context, interval time.Duration)
+	ExportProducts(ctx context.Context, after string, send func(Product) error) error
+	ImportProducts(ctx context.Context, products []Product) error
+	GetAttributeDefinitions(ctx context.Context) ([]AttributeDefinition, error)
+	PutAttributeDefinition(ctx context.Context, d AttributeDefinition) (*AttributeDefinition, error)
+	DeleteAttributeDefinition(ctx context.Context, name string) error
+	SetProductAttributes(ctx context.Context, id string, attributes map[string]interface{}) (*Product, error)
+	GetFacets(ctx context.Context, filter ProductFilter, names []string) ([]Facet, error)
+	SetProductTranslations(ctx context.Context, id string, translations map[string]Translation) (*Product, error)
+	GetProductBySlug(ctx context.Context, slug string) (*Product, bool, error)
+	ChangeProductSlug(ctx context.Context, id string, slug string) (*Product, error)
+	SetProductBundle(ctx context.Context, id string, bundle *Bundle) (*Product, error)
 }
 
 type Product struct {
 	ID          string  `json:"id"`
+	Slug        string  `json:"slug"`
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
+	Images      []Image `json:"images"`
+	Rating      Rating  `json:"rating"`
+	// Status decides whether the public sees the product. PublishAt and
+	// UnpublishAt schedule the next change of it.
+	Status      ProductStatus `json:"status"`
+	PublishAt   *time.Time    `json:"publish_at"`
+	UnpublishAt *time.Time    `json:"unpublish_at"`
+	// Attributes holds a string, float64 or bool per defined attribute.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// Translations holds name and description per locale other than the
+	// default one.
+	Translations map[string]Translation `json:"translations,omitempty"`
+	// Bundle is set when the product is a kit of other products.
+	Bundle *Bundle `json:"bundle,omitempty"`
+}
+
+// SearchHit is a product matched by a text search together with its
+// relevance score and the highlighted fragments of each matching field.
+type SearchHit struct {
+	Product    Product             `json:"product"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights"`
 }
 
 type catalogService struct {
 	repository Repository
+	storage    Storage
+	orders     OrderHistory
+	changes    *changeNotifier
+	reindexing *sync.Mutex
 }
 
-func NewService(r Repository) *catalogService {
-	return &catalogService{r}
+func NewService(r Repository, st Storage, oh OrderHistory) *catalogService {
+	return &catalogService{r, st, oh, newChangeNotifier(), &sync.Mutex{}}
 }
 
 func (s *catalogService) PostProduct(ctx context.Context, name string, description string, price float64) (*Product, error) {
@@ -35,10 +93,17 @@ func (s *catalogService) PostProduct(ctx context.Context, name string, descripti
 		Name:        name,
 		Description: description,
 		Price:       price,
+		Status:      StatusDraft,
+	}
+	slug, err := s.claimSlug(ctx, name, p.ID)
+	if err != nil {
+		return nil, err
 	}
+	p.Slug = slug
 	if err := s.repository.PutProduct(ctx, *p); err != nil {
 		return nil, err
 	}
+	s.changes.notify()
 
 	return p, nil
 }
@@ -51,16 +116,165 @@ func (s *catalogService) GetProducts(ctx context.Context, skip uint64, take uint
 	if skip > 100 || (take == 0 && skip == 0) {
 		take = 100
 	}
-	return s.repository.ListProducts(ctx, skip, take)
+	hits, err := s.repository.FilterProducts(ctx, publishedFilter(ProductFilter{}), skip, take)
+	if err != nil {
+		return nil, err
+	}
+
+	products := []Product{}
+	for _, h := range hits {
+		products = append(products, h.Product)
+	}
+	return products, nil
 }
 
 func (s *catalogService) GetProductsWithIDs(ctx context.Context, ids []string) ([]Product, error) {
 	return s.repository.ListProductWithIDs(ctx, ids)
 }
 
-func (s *catalogService) SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]Product, error) {
+func (s *catalogService) SearchProducts(ctx context.Context, query string, skip uint64, take uint64) ([]SearchHit, error) {
+	return s.FilterProducts(ctx, publishedFilter(ProductFilter{Query: query}), skip, take)
+}
+
+func (s *catalogService) FilterProducts(ctx context.Context, filter ProductFilter, skip uint64, take uint64) ([]SearchHit, error) {
 	if skip > 100 || (take == 0 && skip == 0) {
 		take = 100
 	}
-	return s.repository.SearchProducts(ctx, query, skip, take)
+	return s.repository.FilterProducts(ctx, filter, skip, take)
+}
+
+func (s *catalogService) AddProductImage(ctx context.Context, productID string, altText string, position int, r io.Reader) (*Image, error) {
+	p, err := s.repository.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, img, contentType, err := readImage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	image := Image{
+		ID:          ksuid.New().String(),
+		AltText:     altText,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Thumbnails:  []Thumbnail{},
+	}
+
+	key := imageKey(p.ID, image.ID, "original", imageExtensions[contentType])
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	image.URL = s.storage.URL(key)
+
+	for _, size := range thumbnailSizes {
+		thumb := scaleImage(img, size.Max)
+		data, err := encodeImage(thumb, contentType)
+		if err != nil {
+			s.deleteImageBlobs(ctx, p.ID, image)
+			return nil, err
+		}
+
+		key := imageKey(p.ID, image.ID, size.Name, thumbnailExtension(contentType))
+		if err := s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+			s.deleteImageBlobs(ctx, p.ID, image)
+			return nil, err
+		}
+		image.Thumbnails = append(image.Thumbnails, Thumbnail{
+			Size:   size.Name,
+			URL:    s.storage.URL(key),
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+		})
+	}
+
+	p, err = s.repository.UpdateProductImages(ctx, p.ID, func(images []Image) ([]Image, error) {
+		return insertImage(images, image, position), nil
+	})
+	if err != nil {
+		s.deleteImageBlobs(ctx, productID, image)
+		return nil, err
+	}
+	s.changes.notify()
+
+	for _, i := range p.Images {
+		if i.ID == image.ID {
+			return &i, nil
+		}
+	}
+
+	return &image, nil
+}
+
+// UpdateProductImage changes the alt text and position of an image, nil
+// leaves them as they are.
+func (s *catalogService) UpdateProductImage(ctx context.Context, productID string, imageID string, altText *string, position *int) (*Product, error) {
+	p, err := s.repository.UpdateProductImages(ctx, productID, func(images []Image) ([]Image, error) {
+		index := findImage(images, imageID)
+		if index < 0 {
+			return nil, ErrNotFound
+		}
+
+		image := images[index]
+		if altText != nil {
+			image.AltText = *altText
+		}
+		pos := index
+		if position != nil {
+			pos = *position
+		}
+		return insertImage(append(images[:index:index], images[index+1:]...), image, pos), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	return p, nil
+}
+
+func (s *catalogService) DeleteProductImage(ctx context.Context, productID string, imageID string) (*Product, error) {
+	var image Image
+	p, err := s.repository.UpdateProductImages(ctx, productID, func(images []Image) ([]Image, error) {
+		index := findImage(images, imageID)
+		if index < 0 {
+			return nil, ErrNotFound
+		}
+
+		image = images[index]
+		return renumberImages(append(images[:index:index], images[index+1:]...)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	s.deleteImageBlobs(ctx, p.ID, image)
+	return p, nil
+}
+
+func (s *catalogService) deleteImageBlobs(ctx context.Context, productID string, image Image) {
+	keys := []string{imageKey(productID, image.ID, "original", imageExtensions[image.ContentType])}
+	for _, size := range thumbnailSizes {
+		keys = append(keys, imageKey(productID, image.ID, size.Name, thumbnailExtension(image.ContentType)))
+	}
+
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Println("Error deleting media: ", err)
+		}
+	}
+}
+
+func findImage(images []Image, id string) int {
+	for i, image := range images {
+		if image.ID == id {
+			return i
+		}
+	}
+
+	return -1
 }
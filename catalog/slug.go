@@ -0,0 +1,183 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	maxSlugLength = 80
+	// After this many numbered attempts a random suffix ends the search.
+	maxSlugAttempts = 20
+)
+
+var (
+	ErrSlugTaken   = errors.New("Slug is taken by another product")
+	ErrInvalidSlug = errors.New("Invalid slug")
+)
+
+var slugFolding = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// slugify turns a product name into lowercase ASCII words joined by hyphens.
+func slugify(name string) string {
+	b := strings.Builder{}
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			b.WriteRune(r)
+			hyphen = false
+		case slugFolding[r] != "":
+			b.WriteString(slugFolding[r])
+			hyphen = false
+		case !hyphen && b.Len() > 0:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		slug = "product"
+	}
+	return slug
+}
+
+// claimSlug reserves the first free slug derived from name for a product,
+// appending -2, -3 and so on when it is taken.
+func (s *catalogService) claimSlug(ctx context.Context, name string, productID string) (string, error) {
+	base := slugify(name)
+	for i := 1; i <= maxSlugAttempts; i++ {
+		slug := base
+		if i > 1 {
+			slug = base + "-" + strconv.Itoa(i)
+		}
+		err := s.repository.CreateSlug(ctx, slug, productID)
+		if err == nil {
+			return slug, nil
+		}
+		if err != ErrSlugTaken {
+			return "", err
+		}
+	}
+
+	slug := base + "-" + strings.ToLower(ksuid.New().String()[:8])
+	if err := s.repository.CreateSlug(ctx, slug, productID); err != nil {
+		return "", err
+	}
+	return slug, nil
+}
+
+// slugOwner returns the existing product that has or had slug, an empty id
+// when there is none.
+func (s *catalogService) slugOwner(ctx context.Context, slug string) (string, error) {
+	id, err := s.repository.GetSlug(ctx, slug)
+	if err == ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.repository.GetProductByID(ctx, id)
+	if err == ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetProductBySlug finds a product by its current or any earlier slug. moved
+// reports an earlier one, callers should redirect to the product's slug.
+func (s *catalogService) GetProductBySlug(ctx context.Context, slug string) (*Product, bool, error) {
+	id, err := s.repository.GetSlug(ctx, slug)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p, err := s.repository.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return p, p.Slug != slug, nil
+}
+
+// ChangeProductSlug gives a product a new slug, derived from its name when
+// slug is empty. The old slug keeps pointing at the product.
+func (s *catalogService) ChangeProductSlug(ctx context.Context, id string, slug string) (*Product, error) {
+	p, err := s.repository.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if slug == "" {
+		if slug, err = s.claimSlug(ctx, p.Name, id); err != nil {
+			return nil, err
+		}
+	} else {
+		if slug != slugify(slug) {
+			return nil, ErrInvalidSlug
+		}
+		owner, err := s.repository.GetSlug(ctx, slug)
+		switch {
+		case err == ErrNotFound:
+			if err := s.repository.CreateSlug(ctx, slug, id); err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		case owner != id:
+			return nil, ErrSlugTaken
+		}
+	}
+
+	if err := s.repository.UpdateProductSlug(ctx, id, slug); err != nil {
+		return nil, err
+	}
+	s.changes.notify()
+
+	p.Slug = slug
+	return p, nil
+}
+
+// BackfillSlugs gives every product without a slug one derived from its
+// name, products from before slugs have none. It returns how many it gave.
+func (s *catalogService) BackfillSlugs(ctx context.Context) (int, error) {
+	n := 0
+	after := ""
+	for {
+		products, err := s.repository.ScanProducts(ctx, after, 100)
+		if err != nil {
+			return n, err
+		}
+		if len(products) == 0 {
+			return n, nil
+		}
+
+		for _, p := range products {
+			after = p.ID
+			if p.Slug != "" {
+				continue
+			}
+			if _, err := s.ChangeProductSlug(ctx, p.ID, ""); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+}
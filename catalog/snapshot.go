@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrMissingProductID = errors.New("Imported product has no id")
+
+const exportBatchSize = 500
+
+// ExportProducts calls send for every product in every status, ordered by id
+// and starting after the given id so an interrupted export can resume.
+func (s *catalogService) ExportProducts(ctx context.Context, after string, send func(Product) error) error {
+	for {
+		products, err := s.repository.ScanProducts(ctx, after, exportBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range products {
+			if err := send(p); err != nil {
+				return err
+			}
+			after = p.ID
+		}
+
+		if len(products) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// ImportProducts writes products exactly as given, keeping their ids and
+// pointing their slugs at them. A slug that another existing product has or
+// had fails the import with ErrSlugTaken before anything is written. Image
+// URLs are copied as they are, the media files themselves are not.
+func (s *catalogService) ImportProducts(ctx context.Context, products []Product) error {
+	owners := map[string]string{}
+	for _, p := range products {
+		if p.ID == "" {
+			return ErrMissingProductID
+		}
+		if p.Status != "" && !p.Status.valid() {
+			return ErrInvalidStatus
+		}
+		if p.Slug == "" {
+			continue
+		}
+
+		owner, ok := owners[p.Slug]
+		if !ok {
+			var err error
+			if owner, err = s.slugOwner(ctx, p.Slug); err != nil {
+				return err
+			}
+		}
+		if owner != "" && owner != p.ID {
+			return fmt.Errorf("%w: %s belongs to %s, not %s", ErrSlugTaken, p.Slug, owner, p.ID)
+		}
+		owners[p.Slug] = p.ID
+	}
+
+	for _, p := range products {
+		if p.Slug != "" {
+			if err := s.repository.PutSlug(ctx, p.Slug, p.ID); err != nil {
+				return err
+			}
+		}
+		if err := s.repository.PutProduct(ctx, p); err != nil {
+			return err
+		}
+	}
+	if len(products) > 0 {
+		s.changes.notify()
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage keeps the binary blobs behind product media. Keys are slash
+// separated paths such as "products/<id>/<image>/original.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+type localStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage stores blobs under dir and builds URLs by appending the key
+// to baseURL, which is expected to be served by an HTTP file server.
+func NewLocalStorage(dir string, baseURL string) (Storage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &localStorage{dir, strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *localStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(filepath.Clean("/"+key)))
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, r io.Reader) (err error) {
+	p := s.path(key)
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *localStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *localStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// MediaHandler serves the blobs stored under dir. Directories are not
+// listed, they are not found.
+func MediaHandler(dir string) http.Handler {
+	return http.FileServer(filesOnly{http.Dir(dir)})
+}
+
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+	return file, nil
+}
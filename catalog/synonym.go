@@ -0,0 +1,88 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/segmentio/ksuid"
+)
+
+var ErrInvalidSynonym = errors.New("Synonym needs at least two distinct terms without commas or =>")
+
+// Synonym is a set of terms search treats as equivalent, so a query for one
+// of them also finds products mentioning the others.
+type Synonym struct {
+	ID    string   `json:"id"`
+	Terms []string `json:"terms"`
+}
+
+// rule formats the synonym in the Solr syntax the synonym filter expects.
+func (s Synonym) rule() string {
+	return strings.Join(s.Terms, ", ")
+}
+
+func normalizeTerms(terms []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, t := range terms {
+		t = strings.ToLower(strings.Join(strings.Fields(t), " "))
+		if t == "" || strings.Contains(t, ",") || strings.Contains(t, "=>") {
+			return nil, ErrInvalidSynonym
+		}
+		if !seen[t] {
+			seen[t] = true
+			normalized = append(normalized, t)
+		}
+	}
+	if len(normalized) < 2 {
+		return nil, ErrInvalidSynonym
+	}
+	return normalized, nil
+}
+
+func (s *catalogService) SuggestQuery(ctx context.Context, query string) (string, error) {
+	return s.repository.SuggestQuery(ctx, query)
+}
+
+func (s *catalogService) GetSynonyms(ctx context.Context) ([]Synonym, error) {
+	return s.repository.ListSynonyms(ctx)
+}
+
+// PutSynonym creates a synonym when id is empty and replaces it otherwise.
+// The product index is rebuilt before it returns so the change is live.
+func (s *catalogService) PutSynonym(ctx context.Context, id string, terms []string) (*Synonym, error) {
+	terms, err := normalizeTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		id = ksuid.New().String()
+	}
+
+	synonym := &Synonym{ID: id, Terms: terms}
+	if err := s.repository.PutSynonym(ctx, *synonym); err != nil {
+		return nil, err
+	}
+	if err := s.reindex(ctx); err != nil {
+		return nil, err
+	}
+
+	return synonym, nil
+}
+
+func (s *catalogService) DeleteSynonym(ctx context.Context, id string) error {
+	if err := s.repository.DeleteSynonym(ctx, id); err != nil {
+		return err
+	}
+	return s.reindex(ctx)
+}
+
+// reindex keeps synonym edits on this instance from rebuilding the index
+// concurrently, the second swap would find its old index already gone.
+func (s *catalogService) reindex(ctx context.Context) error {
+	s.reindexing.Lock()
+	defer s.reindexing.Unlock()
+
+	return s.repository.Reindex(ctx)
+}
@@ -22,24 +22,7 @@ func (ar *accountResolver) Orders(ctx context.Context, obj *Account) ([]*Order,
 
 	var orders []*Order
 	for _, o := range orderList {
-		var products []*OrderedProduct
-		for _, p := range o.Products {
-			products = append(products, &OrderedProduct{
-				ID:          p.ID,
-				Name:        p.Name,
-				Description: p.Description,
-				Price:       p.Price,
-				Quantity:    int(p.Quantity),
-			})
-		}
-
-		orders = append(orders, &Order{
-			ID:         o.ID,
-			CreatedAt:  o.CreatedAt,
-			TotalPrice: o.TotalPrice,
-			Products:   products,
-		})
-
+		orders = append(orders, toOrder(&o))
 	}
 
 	return orders, nil
@@ -56,6 +56,12 @@ func (s *Server) Account() AccountResolver {
 	}
 }
 
+func (s *Server) Product() ProductResolver {
+	return &productResolver{
+		server: s,
+	}
+}
+
 func (s *Server) ToExecutableSchema() graphql.ExecutableSchema {
 	return NewExecutableSchema(Config{
 		Resolvers: s,
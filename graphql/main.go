@@ -7,6 +7,7 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/99designs/gqlgen/handler"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
 )
 
 type AppConfig struct {
@@ -27,9 +28,19 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	http.Handle("/graphql", handler.GraphQL(s.ToExecutableSchema()))
+	http.Handle("/graphql", withLocale(handler.GraphQL(s.ToExecutableSchema())))
 	http.Handle("/playground", playground.Handler("Tim", "graphql"))
 
 	log.Fatal(http.ListenAndServe(":8080", nil))
 
 }
+
+// withLocale serves product names and descriptions in the language the
+// client prefers.
+func withLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := catalog.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(catalog.WithLocale(r.Context(), locale)))
+	})
+}
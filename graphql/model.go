@@ -1,7 +1,233 @@
 package main
 
+import (
+	"strings"
+
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+	"github.com/timothydzokoto/grpc_graphql_microservice/order"
+)
+
 type Account struct {
 	ID     string  `json:"id"`
 	Name   string  `json:"name"`
 	Orders []Order `json:"orders"`
 }
+
+func toProduct(p *catalog.Product) *Product {
+	images := []*ProductImage{}
+	for _, i := range p.Images {
+		images = append(images, toProductImage(&i))
+	}
+
+	return &Product{
+		ID:          p.ID,
+		Slug:        p.Slug,
+		Name:        p.Name,
+		Price:       p.Price,
+		Description: p.Description,
+		Images:      images,
+		Rating: &Rating{
+			Average: p.Rating.Average,
+			Count:   int(p.Rating.Count),
+		},
+		Status:      ProductStatus(strings.ToUpper(string(p.Status))),
+		PublishAt:   p.PublishAt,
+		UnpublishAt: p.UnpublishAt,
+		Kind:        ProductKind(strings.ToUpper(string(p.Kind()))),
+		Bundle:      toBundle(p.Bundle),
+	}
+}
+
+func toBundle(b *catalog.Bundle) *Bundle {
+	if b == nil {
+		return nil
+	}
+
+	components := []*BundleComponent{}
+	for _, c := range b.Components {
+		components = append(components, &BundleComponent{ProductID: c.ProductID, Quantity: int(c.Quantity)})
+	}
+	return &Bundle{
+		Components: components,
+		Pricing:    BundlePricing(strings.ToUpper(string(b.Pricing))),
+		Discount:   b.Discount,
+	}
+}
+
+func toProductImage(i *catalog.Image) *ProductImage {
+	thumbnails := []*ProductThumbnail{}
+	for _, t := range i.Thumbnails {
+		thumbnails = append(thumbnails, &ProductThumbnail{
+			Size:   t.Size,
+			URL:    t.URL,
+			Width:  t.Width,
+			Height: t.Height,
+		})
+	}
+
+	return &ProductImage{
+		ID:         i.ID,
+		URL:        i.URL,
+		AltText:    i.AltText,
+		Position:   i.Position,
+		Width:      i.Width,
+		Height:     i.Height,
+		Thumbnails: thumbnails,
+	}
+}
+
+func toOrder(o *order.Order) *Order {
+	products := []*OrderedProduct{}
+	for _, p := range o.Products {
+		line := &OrderedProduct{
+			ID:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price,
+			Quantity:    int(p.Quantity),
+			Currency:    p.Currency,
+		}
+		if p.ImageURL != "" {
+			imageURL := p.ImageURL
+			line.ImageURL = &imageURL
+		}
+		products = append(products, line)
+	}
+
+	history := []*OrderStatusChange{}
+	for _, c := range o.History {
+		change := &OrderStatusChange{
+			To:        toOrderStatus(c.To),
+			Actor:     c.Actor,
+			CreatedAt: c.CreatedAt,
+		}
+		if c.From != "" {
+			from := toOrderStatus(c.From)
+			change.From = &from
+		}
+		history = append(history, change)
+	}
+
+	refunds := []*Refund{}
+	for _, r := range o.Refunds {
+		lines := []*RefundLine{}
+		for _, l := range r.Lines {
+			lines = append(lines, &RefundLine{ProductID: l.ProductID, Quantity: int(l.Quantity), Amount: l.Amount})
+		}
+		refunds = append(refunds, &Refund{
+			ID:        r.ID,
+			Amount:    r.Amount,
+			Reason:    r.Reason,
+			Actor:     r.Actor,
+			Lines:     lines,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	taxLines := []*TaxLine{}
+	for _, t := range o.TaxLines {
+		taxLines = append(taxLines, &TaxLine{
+			ProductID:    t.ProductID,
+			TaxClass:     t.TaxClass,
+			Jurisdiction: t.Jurisdiction,
+			Rate:         t.Rate,
+			Taxable:      t.Taxable,
+			Amount:       t.Amount,
+		})
+	}
+
+	discounts := []*OrderDiscount{}
+	for _, d := range o.Discounts {
+		discounts = append(discounts, &OrderDiscount{ProductID: d.ProductID, Code: d.Code, Amount: d.Amount})
+	}
+
+	return &Order{
+		ID:         o.ID,
+		Products:   products,
+		TotalPrice: o.TotalPrice,
+		CreatedAt:  o.CreatedAt,
+		Status:     toOrderStatus(o.Status),
+		History:    history,
+		Refunds:    refunds,
+		NetTotal:   o.NetTotal(),
+		Subtotal:   o.Subtotal(),
+		Discounts:  discounts,
+		TaxMode:    TaxMode(strings.ToUpper(string(o.TaxMode))),
+		TaxTotal:   o.TaxTotal(),
+		TaxLines:   taxLines,
+	}
+}
+
+func toAddress(a *AddressInput) order.Address {
+	if a == nil {
+		return order.Address{}
+	}
+	address := order.Address{Country: strings.ToUpper(a.Country)}
+	if a.Region != nil {
+		address.Region = strings.ToUpper(*a.Region)
+	}
+	return address
+}
+
+func toOrderStatus(s order.OrderStatus) OrderStatus {
+	return OrderStatus(strings.ToUpper(string(s)))
+}
+
+func toCart(c *order.Cart) *Cart {
+	cart := &Cart{
+		ID:        c.ID,
+		Lines:     []*CartLine{},
+		Total:     c.Total(),
+		Currency:  order.DefaultCurrency,
+		ExpiresAt: c.ExpiresAt,
+	}
+	if c.AccountID != "" {
+		accountID := c.AccountID
+		cart.AccountID = &accountID
+	}
+	if c.Token != "" {
+		token := c.Token
+		cart.Token = &token
+	}
+	for _, l := range c.Lines {
+		cart.Lines = append(cart.Lines, &CartLine{
+			ProductID: l.ProductID,
+			Name:      l.Name,
+			Price:     l.Price,
+			Quantity:  int(l.Quantity),
+			Available: l.Available,
+		})
+	}
+	return cart
+}
+
+func cartOwner(accountID *string, token *string) order.CartOwner {
+	owner := order.CartOwner{}
+	if accountID != nil {
+		owner.AccountID = *accountID
+	}
+	if token != nil {
+		owner.Token = *token
+	}
+	return owner
+}
+
+func toPromotion(p *order.Promotion) *Promotion {
+	promotion := &Promotion{
+		Code:              p.Code,
+		Kind:              PromotionKind(strings.ToUpper(string(p.Kind))),
+		Value:             p.Value,
+		BuyQuantity:       int(p.BuyQuantity),
+		GetQuantity:       int(p.GetQuantity),
+		Stackable:         p.Stackable,
+		StartsAt:          p.StartsAt,
+		EndsAt:            p.EndsAt,
+		MaxUses:           int(p.MaxUses),
+		MaxUsesPerAccount: int(p.MaxUsesPerAccount),
+	}
+	if p.ProductID != "" {
+		productID := p.ProductID
+		promotion.ProductID = &productID
+	}
+	return promotion
+}
@@ -3,26 +3,124 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 )
 
 type AccountInput struct {
 	Name string `json:"name"`
 }
 
+type AddressInput struct {
+	Country string  `json:"country"`
+	Region  *string `json:"region,omitempty"`
+}
+
+type Bundle struct {
+	Components []*BundleComponent `json:"components"`
+	Pricing    BundlePricing      `json:"pricing"`
+	Discount   float64            `json:"discount"`
+}
+
+type BundleComponent struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+type BundleComponentInput struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+type CancelOrderInput struct {
+	OrderID   string  `json:"orderId"`
+	AccountID string  `json:"accountId"`
+	Reason    *string `json:"reason,omitempty"`
+}
+
+type Cart struct {
+	ID        string      `json:"id"`
+	AccountID *string     `json:"accountId,omitempty"`
+	Token     *string     `json:"token,omitempty"`
+	Lines     []*CartLine `json:"lines"`
+	Total     float64     `json:"total"`
+	Currency  string      `json:"currency"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+}
+
+type CartLine struct {
+	ProductID string  `json:"productId"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Quantity  int     `json:"quantity"`
+	Available bool    `json:"available"`
+}
+
+type CartLineInput struct {
+	AccountID *string `json:"accountId,omitempty"`
+	Token     *string `json:"token,omitempty"`
+	ProductID string  `json:"productId"`
+	Quantity  int     `json:"quantity"`
+}
+
+type CheckoutCartInput struct {
+	AccountID       string        `json:"accountId"`
+	Token           *string       `json:"token,omitempty"`
+	ApplyCoupon     []string      `json:"applyCoupon,omitempty"`
+	ShippingAddress *AddressInput `json:"shippingAddress,omitempty"`
+}
+
 type Mutation struct {
 }
 
 type Order struct {
-	ID         string            `json:"id"`
-	Products   []*OrderedProduct `json:"products"`
-	TotalPrice float64           `json:"total_price"`
-	CreatedAt  time.Time         `json:"createdAt"`
+	ID         string               `json:"id"`
+	Products   []*OrderedProduct    `json:"products"`
+	TotalPrice float64              `json:"total_price"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	Status     OrderStatus          `json:"status"`
+	History    []*OrderStatusChange `json:"history"`
+	Refunds    []*Refund            `json:"refunds"`
+	NetTotal   float64              `json:"netTotal"`
+	Subtotal   float64              `json:"subtotal"`
+	Discounts  []*OrderDiscount     `json:"discounts"`
+	TaxMode    TaxMode              `json:"taxMode"`
+	TaxTotal   float64              `json:"taxTotal"`
+	TaxLines   []*TaxLine           `json:"taxLines"`
+}
+
+type OrderDiscount struct {
+	ProductID string  `json:"productId"`
+	Code      string  `json:"code"`
+	Amount    float64 `json:"amount"`
+}
+
+type OrderFilterInput struct {
+	AccountID     *string       `json:"accountId,omitempty"`
+	Statuses      []OrderStatus `json:"statuses,omitempty"`
+	CreatedAfter  *time.Time    `json:"createdAfter,omitempty"`
+	CreatedBefore *time.Time    `json:"createdBefore,omitempty"`
+	MinTotal      *float64      `json:"minTotal,omitempty"`
+	MaxTotal      *float64      `json:"maxTotal,omitempty"`
+	ProductID     *string       `json:"productId,omitempty"`
 }
 
 type OrderInput struct {
-	AccountID string                 `json:"accountId"`
-	Products  []*OrderedProductInput `json:"products"`
+	AccountID       string                 `json:"accountId"`
+	Products        []*OrderedProductInput `json:"products"`
+	ApplyCoupon     []string               `json:"applyCoupon,omitempty"`
+	ShippingAddress *AddressInput          `json:"shippingAddress,omitempty"`
+}
+
+type OrderStatusChange struct {
+	From      *OrderStatus `json:"from,omitempty"`
+	To        OrderStatus  `json:"to"`
+	Actor     string       `json:"actor"`
+	CreatedAt time.Time    `json:"createdAt"`
 }
 
 type OrderedProduct struct {
@@ -31,6 +129,8 @@ type OrderedProduct struct {
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	Quantity    int     `json:"quantity"`
+	Currency    string  `json:"currency"`
+	ImageURL    *string `json:"imageUrl,omitempty"`
 }
 
 type OrderedProductInput struct {
@@ -44,10 +144,44 @@ type PaginationInput struct {
 }
 
 type Product struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description"`
+	ID          string            `json:"id"`
+	Slug        string            `json:"slug"`
+	Name        string            `json:"name"`
+	Price       float64           `json:"price"`
+	Description string            `json:"description"`
+	Images      []*ProductImage   `json:"images"`
+	Rating      *Rating           `json:"rating"`
+	Status      ProductStatus     `json:"status"`
+	PublishAt   *time.Time        `json:"publishAt,omitempty"`
+	UnpublishAt *time.Time        `json:"unpublishAt,omitempty"`
+	Kind        ProductKind       `json:"kind"`
+	Bundle      *Bundle           `json:"bundle,omitempty"`
+	Reviews     *ReviewConnection `json:"reviews"`
+	Related     []*Product        `json:"related"`
+}
+
+type ProductBundleInput struct {
+	ProductID  string                  `json:"productId"`
+	Components []*BundleComponentInput `json:"components,omitempty"`
+	Pricing    *BundlePricing          `json:"pricing,omitempty"`
+	Discount   *float64                `json:"discount,omitempty"`
+}
+
+type ProductImage struct {
+	ID         string              `json:"id"`
+	URL        string              `json:"url"`
+	AltText    string              `json:"altText"`
+	Position   int                 `json:"position"`
+	Width      int                 `json:"width"`
+	Height     int                 `json:"height"`
+	Thumbnails []*ProductThumbnail `json:"thumbnails"`
+}
+
+type ProductImageInput struct {
+	ProductID string         `json:"productId"`
+	File      graphql.Upload `json:"file"`
+	AltText   *string        `json:"altText,omitempty"`
+	Position  *int           `json:"position,omitempty"`
 }
 
 type ProductInput struct {
@@ -56,5 +190,393 @@ type ProductInput struct {
 	Description string  `json:"description"`
 }
 
+type ProductSearchHit struct {
+	Product    *Product           `json:"product"`
+	Score      float64            `json:"score"`
+	Highlights []*SearchHighlight `json:"highlights"`
+}
+
+type ProductSearchResult struct {
+	Hits       []*ProductSearchHit `json:"hits"`
+	Suggestion *string             `json:"suggestion,omitempty"`
+}
+
+type ProductStatusInput struct {
+	ProductID   string        `json:"productId"`
+	Status      ProductStatus `json:"status"`
+	PublishAt   *time.Time    `json:"publishAt,omitempty"`
+	UnpublishAt *time.Time    `json:"unpublishAt,omitempty"`
+}
+
+type ProductThumbnail struct {
+	Size   string `json:"size"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type Promotion struct {
+	Code              string        `json:"code"`
+	Kind              PromotionKind `json:"kind"`
+	Value             float64       `json:"value"`
+	ProductID         *string       `json:"productId,omitempty"`
+	BuyQuantity       int           `json:"buyQuantity"`
+	GetQuantity       int           `json:"getQuantity"`
+	Stackable         bool          `json:"stackable"`
+	StartsAt          *time.Time    `json:"startsAt,omitempty"`
+	EndsAt            *time.Time    `json:"endsAt,omitempty"`
+	MaxUses           int           `json:"maxUses"`
+	MaxUsesPerAccount int           `json:"maxUsesPerAccount"`
+}
+
+type PromotionInput struct {
+	Code              string        `json:"code"`
+	Kind              PromotionKind `json:"kind"`
+	Value             *float64      `json:"value,omitempty"`
+	ProductID         *string       `json:"productId,omitempty"`
+	BuyQuantity       *int          `json:"buyQuantity,omitempty"`
+	GetQuantity       *int          `json:"getQuantity,omitempty"`
+	Stackable         *bool         `json:"stackable,omitempty"`
+	StartsAt          *time.Time    `json:"startsAt,omitempty"`
+	EndsAt            *time.Time    `json:"endsAt,omitempty"`
+	MaxUses           *int          `json:"maxUses,omitempty"`
+	MaxUsesPerAccount *int          `json:"maxUsesPerAccount,omitempty"`
+}
+
 type Query struct {
 }
+
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+type Refund struct {
+	ID        string        `json:"id"`
+	Amount    float64       `json:"amount"`
+	Reason    string        `json:"reason"`
+	Actor     string        `json:"actor"`
+	Lines     []*RefundLine `json:"lines"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+type RefundLine struct {
+	ProductID string  `json:"productId"`
+	Quantity  int     `json:"quantity"`
+	Amount    float64 `json:"amount"`
+}
+
+type RefundLineInput struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+type RefundOrderInput struct {
+	OrderID string             `json:"orderId"`
+	Lines   []*RefundLineInput `json:"lines,omitempty"`
+	Actor   string             `json:"actor"`
+	Reason  string             `json:"reason"`
+}
+
+type RemoveCartLineInput struct {
+	AccountID *string `json:"accountId,omitempty"`
+	Token     *string `json:"token,omitempty"`
+	ProductID string  `json:"productId"`
+}
+
+type Review struct {
+	ID        string    `json:"id"`
+	ProductID string    `json:"productId"`
+	AccountID string    `json:"accountId"`
+	Rating    int       `json:"rating"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReviewConnection struct {
+	Reviews []*Review `json:"reviews"`
+	Total   int       `json:"total"`
+}
+
+type ReviewInput struct {
+	ProductID string `json:"productId"`
+	AccountID string `json:"accountId"`
+	Rating    int    `json:"rating"`
+	Body      string `json:"body"`
+}
+
+type SearchHighlight struct {
+	Field     string   `json:"field"`
+	Fragments []string `json:"fragments"`
+}
+
+type TaxLine struct {
+	ProductID    string  `json:"productId"`
+	TaxClass     string  `json:"taxClass"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Rate         float64 `json:"rate"`
+	Taxable      float64 `json:"taxable"`
+	Amount       float64 `json:"amount"`
+}
+
+type BundlePricing string
+
+const (
+	BundlePricingFixed      BundlePricing = "FIXED"
+	BundlePricingPercentOff BundlePricing = "PERCENT_OFF"
+	BundlePricingAmountOff  BundlePricing = "AMOUNT_OFF"
+)
+
+var AllBundlePricing = []BundlePricing{
+	BundlePricingFixed,
+	BundlePricingPercentOff,
+	BundlePricingAmountOff,
+}
+
+func (e BundlePricing) IsValid() bool {
+	switch e {
+	case BundlePricingFixed, BundlePricingPercentOff, BundlePricingAmountOff:
+		return true
+	}
+	return false
+}
+
+func (e BundlePricing) String() string {
+	return string(e)
+}
+
+func (e *BundlePricing) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BundlePricing(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BundlePricing", str)
+	}
+	return nil
+}
+
+func (e BundlePricing) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "PENDING"
+	OrderStatusPaid      OrderStatus = "PAID"
+	OrderStatusFulfilled OrderStatus = "FULFILLED"
+	OrderStatusShipped   OrderStatus = "SHIPPED"
+	OrderStatusDelivered OrderStatus = "DELIVERED"
+	OrderStatusCancelled OrderStatus = "CANCELLED"
+	OrderStatusRefunded  OrderStatus = "REFUNDED"
+)
+
+var AllOrderStatus = []OrderStatus{
+	OrderStatusPending,
+	OrderStatusPaid,
+	OrderStatusFulfilled,
+	OrderStatusShipped,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+	OrderStatusRefunded,
+}
+
+func (e OrderStatus) IsValid() bool {
+	switch e {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusFulfilled, OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func (e OrderStatus) String() string {
+	return string(e)
+}
+
+func (e *OrderStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderStatus", str)
+	}
+	return nil
+}
+
+func (e OrderStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ProductKind string
+
+const (
+	ProductKindSimple ProductKind = "SIMPLE"
+	ProductKindBundle ProductKind = "BUNDLE"
+)
+
+var AllProductKind = []ProductKind{
+	ProductKindSimple,
+	ProductKindBundle,
+}
+
+func (e ProductKind) IsValid() bool {
+	switch e {
+	case ProductKindSimple, ProductKindBundle:
+		return true
+	}
+	return false
+}
+
+func (e ProductKind) String() string {
+	return string(e)
+}
+
+func (e *ProductKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductKind", str)
+	}
+	return nil
+}
+
+func (e ProductKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ProductStatus string
+
+const (
+	ProductStatusDraft     ProductStatus = "DRAFT"
+	ProductStatusPublished ProductStatus = "PUBLISHED"
+	ProductStatusArchived  ProductStatus = "ARCHIVED"
+)
+
+var AllProductStatus = []ProductStatus{
+	ProductStatusDraft,
+	ProductStatusPublished,
+	ProductStatusArchived,
+}
+
+func (e ProductStatus) IsValid() bool {
+	switch e {
+	case ProductStatusDraft, ProductStatusPublished, ProductStatusArchived:
+		return true
+	}
+	return false
+}
+
+func (e ProductStatus) String() string {
+	return string(e)
+}
+
+func (e *ProductStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ProductStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ProductStatus", str)
+	}
+	return nil
+}
+
+func (e ProductStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type PromotionKind string
+
+const (
+	PromotionKindPercent  PromotionKind = "PERCENT"
+	PromotionKindFixed    PromotionKind = "FIXED"
+	PromotionKindBuyXGetY PromotionKind = "BUY_X_GET_Y"
+)
+
+var AllPromotionKind = []PromotionKind{
+	PromotionKindPercent,
+	PromotionKindFixed,
+	PromotionKindBuyXGetY,
+}
+
+func (e PromotionKind) IsValid() bool {
+	switch e {
+	case PromotionKindPercent, PromotionKindFixed, PromotionKindBuyXGetY:
+		return true
+	}
+	return false
+}
+
+func (e PromotionKind) String() string {
+	return string(e)
+}
+
+func (e *PromotionKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PromotionKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PromotionKind", str)
+	}
+	return nil
+}
+
+func (e PromotionKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TaxMode string
+
+const (
+	TaxModeExclusive TaxMode = "EXCLUSIVE"
+	TaxModeInclusive TaxMode = "INCLUSIVE"
+)
+
+var AllTaxMode = []TaxMode{
+	TaxModeExclusive,
+	TaxModeInclusive,
+}
+
+func (e TaxMode) IsValid() bool {
+	switch e {
+	case TaxModeExclusive, TaxModeInclusive:
+		return true
+	}
+	return false
+}
+
+func (e TaxMode) String() string {
+	return string(e)
+}
+
+func (e *TaxMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TaxMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TaxMode", str)
+	}
+	return nil
+}
+
+func (e TaxMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
 	"github.com/timothydzokoto/grpc_graphql_microservice/order"
 )
 
@@ -42,11 +44,96 @@ func (r *mutationResolver) CreateProduct(ctx context.Context, in ProductInput) (
 		log.Println("Error creating product: ", err)
 		return nil, err
 	}
-	return &Product{
-		ID:          p.ID,
-		Name:        p.Name,
-		Description: p.Description,
-		Price:       p.Price,
+	return toProduct(p), nil
+}
+
+func (r *mutationResolver) SetProductStatus(ctx context.Context, in ProductStatusInput) (*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	status := catalog.ProductStatus(strings.ToLower(string(in.Status)))
+	p, err := r.server.catalogClient.SetProductStatus(ctx, in.ProductID, status, in.PublishAt, in.UnpublishAt)
+	if err != nil {
+		log.Println("Error setting product status: ", err)
+		return nil, err
+	}
+	return toProduct(p), nil
+}
+
+func (r *mutationResolver) SetProductBundle(ctx context.Context, in ProductBundleInput) (*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	var bundle *catalog.Bundle
+	if in.Components != nil {
+		if in.Pricing == nil {
+			return nil, ErrInvalidParameter
+		}
+		bundle = &catalog.Bundle{Pricing: catalog.BundlePricing(strings.ToLower(string(*in.Pricing)))}
+		if in.Discount != nil {
+			bundle.Discount = *in.Discount
+		}
+		for _, c := range in.Components {
+			if c.Quantity <= 0 {
+				return nil, ErrInvalidParameter
+			}
+			bundle.Components = append(bundle.Components, catalog.BundleComponent{
+				ProductID: c.ProductID,
+				Quantity:  uint64(c.Quantity),
+			})
+		}
+	}
+
+	p, err := r.server.catalogClient.SetProductBundle(ctx, in.ProductID, bundle)
+	if err != nil {
+		log.Println("Error setting product bundle: ", err)
+		return nil, err
+	}
+	return toProduct(p), nil
+}
+
+func (r *mutationResolver) UploadProductImage(ctx context.Context, in ProductImageInput) (*ProductImage, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	altText := ""
+	if in.AltText != nil {
+		altText = *in.AltText
+	}
+	position := -1
+	if in.Position != nil {
+		position = *in.Position
+	}
+
+	i, err := r.server.catalogClient.UploadProductImage(ctx, in.ProductID, altText, position, in.File.File)
+	if err != nil {
+		log.Println("Error uploading product image: ", err)
+		return nil, err
+	}
+	return toProductImage(i), nil
+}
+
+func (r *mutationResolver) CreateReview(ctx context.Context, in ReviewInput) (*Review, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	if in.Rating < 1 || in.Rating > 5 {
+		return nil, ErrInvalidParameter
+	}
+
+	rv, err := r.server.catalogClient.PostReview(ctx, in.ProductID, in.AccountID, in.Rating, in.Body)
+	if err != nil {
+		log.Println("Error creating review: ", err)
+		return nil, err
+	}
+
+	return &Review{
+		ID:        rv.ID,
+		ProductID: rv.ProductID,
+		AccountID: rv.AccountID,
+		Rating:    rv.Rating,
+		Body:      rv.Body,
+		CreatedAt: rv.CreatedAt,
 	}, nil
 }
 
@@ -66,27 +153,168 @@ func (r *mutationResolver) CreateOrder(ctx context.Context, in OrderInput) (*Ord
 
 	}
 
-	o, err := r.server.orderClient.PostOrder(ctx, in.AccountID, products)
+	o, err := r.server.orderClient.PostOrder(ctx, in.AccountID, products, in.ApplyCoupon, toAddress(in.ShippingAddress))
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	var ops []*OrderedProduct
+	return toOrder(o), nil
+}
 
-	for _, op := range o.Products {
-		ops = append(ops, &OrderedProduct{
-			ID:          op.ID,
-			Name:        op.Name,
-			Description: op.Description,
-			Quantity:    int(op.Quantity),
-		})
+func (r *mutationResolver) CancelOrder(ctx context.Context, in CancelOrderInput) (*Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	reason := ""
+	if in.Reason != nil {
+		reason = *in.Reason
 	}
 
-	return &Order{
-		ID:         o.ID,
-		Products:   ops,
-		TotalPrice: o.TotalPrice,
-		CreatedAt:  o.CreatedAt,
-	}, nil
+	o, err := r.server.orderClient.CancelOrder(ctx, in.OrderID, in.AccountID, in.AccountID, reason)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toOrder(o), nil
+}
+
+func (r *mutationResolver) RefundOrder(ctx context.Context, in RefundOrderInput) (*Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	lines := []order.RefundLine{}
+	for _, l := range in.Lines {
+		if l.Quantity <= 0 {
+			return nil, ErrInvalidParameter
+		}
+		lines = append(lines, order.RefundLine{ProductID: l.ProductID, Quantity: uint64(l.Quantity)})
+	}
+
+	o, err := r.server.orderClient.RefundOrder(ctx, in.OrderID, lines, in.Actor, in.Reason)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toOrder(o), nil
+}
+
+func (r *mutationResolver) AddCartLine(ctx context.Context, in CartLineInput) (*Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	if in.Quantity <= 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	c, err := r.server.orderClient.AddCartLine(ctx, cartOwner(in.AccountID, in.Token), in.ProductID, uint64(in.Quantity))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toCart(c), nil
+}
+
+func (r *mutationResolver) UpdateCartLine(ctx context.Context, in CartLineInput) (*Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	if in.Quantity < 0 {
+		return nil, ErrInvalidParameter
+	}
+
+	c, err := r.server.orderClient.UpdateCartLine(ctx, cartOwner(in.AccountID, in.Token), in.ProductID, uint64(in.Quantity))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toCart(c), nil
+}
+
+func (r *mutationResolver) RemoveCartLine(ctx context.Context, in RemoveCartLineInput) (*Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	c, err := r.server.orderClient.RemoveCartLine(ctx, cartOwner(in.AccountID, in.Token), in.ProductID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toCart(c), nil
+}
+
+// MergeCart is called once a guest logs in, moving its cart into the cart
+// of the account.
+func (r *mutationResolver) MergeCart(ctx context.Context, token string, accountID string) (*Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	c, err := r.server.orderClient.MergeCart(ctx, token, accountID)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toCart(c), nil
+}
+
+func (r *mutationResolver) CheckoutCart(ctx context.Context, in CheckoutCartInput) (*Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	token := ""
+	if in.Token != nil {
+		token = *in.Token
+	}
+
+	o, err := r.server.orderClient.CheckoutCart(ctx, in.AccountID, token, in.ApplyCoupon, toAddress(in.ShippingAddress))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toOrder(o), nil
+}
+
+func (r *mutationResolver) CreatePromotion(ctx context.Context, in PromotionInput) (*Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	p := order.Promotion{
+		Code:     in.Code,
+		Kind:     order.PromotionKind(strings.ToLower(string(in.Kind))),
+		StartsAt: in.StartsAt,
+		EndsAt:   in.EndsAt,
+	}
+	if in.Value != nil {
+		p.Value = *in.Value
+	}
+	if in.ProductID != nil {
+		p.ProductID = *in.ProductID
+	}
+	if in.Stackable != nil {
+		p.Stackable = *in.Stackable
+	}
+	for _, v := range []struct {
+		in  *int
+		out *uint64
+	}{
+		{in.BuyQuantity, &p.BuyQuantity},
+		{in.GetQuantity, &p.GetQuantity},
+		{in.MaxUses, &p.MaxUses},
+		{in.MaxUsesPerAccount, &p.MaxUsesPerAccount},
+	} {
+		if v.in == nil {
+			continue
+		}
+		if *v.in < 0 {
+			return nil, ErrInvalidParameter
+		}
+		*v.out = uint64(*v.in)
+	}
+
+	created, err := r.server.orderClient.CreatePromotion(ctx, p)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toPromotion(created), nil
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type productResolver struct {
+	server *Server
+}
+
+func (pr *productResolver) Reviews(ctx context.Context, obj *Product, pagination *PaginationInput) (*ReviewConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	skip, take := uint64(0), uint64(10)
+	if pagination != nil {
+		skip, take = pagination.bounds()
+	}
+
+	reviewList, total, err := pr.server.catalogClient.GetReviews(ctx, obj.ID, skip, take)
+	if err != nil {
+		log.Println("Error getting reviews: ", err)
+		return nil, err
+	}
+
+	reviews := []*Review{}
+	for _, r := range reviewList {
+		reviews = append(reviews, &Review{
+			ID:        r.ID,
+			ProductID: r.ProductID,
+			AccountID: r.AccountID,
+			Rating:    r.Rating,
+			Body:      r.Body,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	return &ReviewConnection{
+		Reviews: reviews,
+		Total:   int(total),
+	}, nil
+}
+
+func (pr *productResolver) Related(ctx context.Context, obj *Product, limit *int) ([]*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	l := uint64(10)
+	if limit != nil {
+		if *limit <= 0 {
+			return nil, ErrInvalidParameter
+		}
+		l = uint64(*limit)
+	}
+
+	productList, err := pr.server.catalogClient.GetRelatedProducts(ctx, obj.ID, l)
+	if err != nil {
+		log.Println("Error getting related products: ", err)
+		return nil, err
+	}
+
+	products := []*Product{}
+	for _, p := range productList {
+		products = append(products, toProduct(&p))
+	}
+
+	return products, nil
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
-	"errors"
 	"log"
+	"strings"
 	"time"
+
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+	"github.com/timothydzokoto/grpc_graphql_microservice/order"
 )
 
 type queryResolver struct {
@@ -59,12 +62,7 @@ func (qr *queryResolver) Products(ctx context.Context, pagination *PaginationInp
 			log.Println(err)
 			return nil, err
 		}
-		return []*Product{{
-			ID:          r.ID,
-			Name:        r.Name,
-			Price:       r.Price,
-			Description: r.Description,
-		}}, nil
+		return []*Product{toProduct(r)}, nil
 	}
 
 	skip, take := uint64(0), uint64(10)
@@ -85,18 +83,98 @@ func (qr *queryResolver) Products(ctx context.Context, pagination *PaginationInp
 
 	var products []*Product
 	for _, p := range productList {
-		products = append(products, &Product{
-			ID:          p.ID,
-			Name:        p.Name,
-			Price:       p.Price,
-			Description: p.Description,
-		})
+		products = append(products, toProduct(&p))
 	}
 
 	return products, nil
 
 }
 
+// Product looks a published product up by its current or an earlier slug.
+// Clients compare the returned slug with the requested one to redirect.
+func (qr *queryResolver) Product(ctx context.Context, slug string) (*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	p, _, err := qr.server.catalogClient.GetProductBySlug(ctx, slug)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if p.Status != catalog.StatusPublished {
+		return nil, nil
+	}
+
+	return toProduct(p), nil
+}
+
+// AdminProducts lists products in every status, unlike Products which only
+// shows published ones.
+func (qr *queryResolver) AdminProducts(ctx context.Context, pagination *PaginationInput, query *string) ([]*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	skip, take := uint64(0), uint64(10)
+	if pagination != nil {
+		skip, take = pagination.bounds()
+	}
+
+	q := ""
+	if query != nil {
+		q = *query
+	}
+
+	productList, err := qr.server.catalogClient.GetAllProducts(ctx, skip, take, q, nil)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	products := []*Product{}
+	for _, p := range productList {
+		products = append(products, toProduct(&p))
+	}
+
+	return products, nil
+}
+
+func (qr *queryResolver) SearchProducts(ctx context.Context, query string, pagination *PaginationInput) (*ProductSearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	skip, take := uint64(0), uint64(10)
+	if pagination != nil {
+		skip, take = pagination.bounds()
+	}
+
+	hitList, suggestion, err := qr.server.catalogClient.SearchProducts(ctx, query, skip, take)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	hits := []*ProductSearchHit{}
+	for _, h := range hitList {
+		highlights := []*SearchHighlight{}
+		for _, field := range []string{"name", "description"} {
+			if fragments, ok := h.Highlights[field]; ok {
+				highlights = append(highlights, &SearchHighlight{Field: field, Fragments: fragments})
+			}
+		}
+		hits = append(hits, &ProductSearchHit{
+			Product:    toProduct(&h.Product),
+			Score:      h.Score,
+			Highlights: highlights,
+		})
+	}
+
+	result := &ProductSearchResult{Hits: hits}
+	if suggestion != "" {
+		result.Suggestion = &suggestion
+	}
+	return result, nil
+}
+
 func (p PaginationInput) bounds() (uint64, uint64) {
 	skip := uint64(0)
 	take := uint64(0)
@@ -110,6 +188,74 @@ func (p PaginationInput) bounds() (uint64, uint64) {
 	return skip, take
 }
 
-func (qr *queryResolver) Orders(ctx context.Context, pagination *PaginationInput, id *string) ([]*Order, error) {
-	return nil, errors.New("No order")
+func (qr *queryResolver) Orders(ctx context.Context, pagination *PaginationInput, id *string, filter *OrderFilterInput) ([]*Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	if id != nil {
+		o, err := qr.server.orderClient.GetOrder(ctx, *id)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		return []*Order{toOrder(o)}, nil
+	}
+
+	skip, take := uint64(0), uint64(10)
+	if pagination != nil {
+		skip, take = pagination.bounds()
+	}
+
+	f := order.OrderFilter{}
+	if filter != nil {
+		if filter.AccountID != nil {
+			f.AccountID = *filter.AccountID
+		}
+		for _, s := range filter.Statuses {
+			f.Statuses = append(f.Statuses, order.OrderStatus(strings.ToLower(string(s))))
+		}
+		f.CreatedAfter = filter.CreatedAfter
+		f.CreatedBefore = filter.CreatedBefore
+		f.MinTotal = filter.MinTotal
+		f.MaxTotal = filter.MaxTotal
+		if filter.ProductID != nil {
+			f.ProductID = *filter.ProductID
+		}
+	}
+
+	orderList, err := qr.server.orderClient.ListOrders(ctx, f, skip, take)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	orders := []*Order{}
+	for i := range orderList {
+		orders = append(orders, toOrder(&orderList[i]))
+	}
+	return orders, nil
+}
+
+func (qr *queryResolver) Cart(ctx context.Context, accountID *string, token *string) (*Cart, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	c, err := qr.server.orderClient.GetCart(ctx, cartOwner(accountID, token))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toCart(c), nil
+}
+
+func (qr *queryResolver) Promotion(ctx context.Context, code string) (*Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*3)
+	defer cancel()
+
+	p, err := qr.server.orderClient.GetPromotion(ctx, code)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return toPromotion(p), nil
 }
@@ -0,0 +1,89 @@
+package order
+
+import (
+	"context"
+	"sort"
+
+	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
+)
+
+// OrderedComponent is a product shipped as part of a bundle line.
+type OrderedComponent struct {
+	ProductID string `json:"product_id"`
+	Quantity  uint64 `json:"quantity"`
+}
+
+// StockLine is a quantity of a product to reserve or release stock for.
+type StockLine struct {
+	ProductID string `json:"product_id"`
+	Quantity  uint64 `json:"quantity"`
+}
+
+// StockLines returns one line per product that physically ships with the
+// order, bundles replaced by their components, ordered by product id so
+// reservations always lock products in the same order.
+func (o Order) StockLines() []StockLine {
+	quantities := map[string]uint64{}
+	for _, p := range o.Products {
+		if len(p.Components) == 0 {
+			quantities[p.ID] += p.Quantity
+			continue
+		}
+		for _, c := range p.Components {
+			quantities[c.ProductID] += c.Quantity
+		}
+	}
+
+	lines := []StockLine{}
+	for id, quantity := range quantities {
+		lines = append(lines, StockLine{ProductID: id, Quantity: quantity})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].ProductID < lines[j].ProductID
+	})
+	return lines
+}
+
+// expandBundles prices every bundle line from the current prices of its
+// components and fills in the components it ships as. The customer still
+// sees a single line per bundle.
+func expandBundles(ctx context.Context, catalogClient *catalog.Client, lines []OrderedProduct, bundles map[string]*catalog.Bundle) error {
+	if len(bundles) == 0 {
+		return nil
+	}
+
+	ids := []string{}
+	for _, b := range bundles {
+		for _, c := range b.Components {
+			ids = append(ids, c.ProductID)
+		}
+	}
+	components, err := catalogClient.GetAllProducts(ctx, 0, 0, "", ids)
+	if err != nil {
+		return err
+	}
+	prices := map[string]float64{}
+	for _, c := range components {
+		prices[c.ID] = c.Price
+	}
+
+	for i, line := range lines {
+		b, ok := bundles[line.ID]
+		if !ok {
+			continue
+		}
+
+		price, err := b.Price(line.Price, prices)
+		if err != nil {
+			return err
+		}
+		lines[i].Price = price
+		for _, c := range b.Components {
+			lines[i].Components = append(lines[i].Components, OrderedComponent{
+				ProductID: c.ProductID,
+				Quantity:  c.Quantity * line.Quantity,
+			})
+		}
+	}
+	return nil
+}
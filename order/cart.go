@@ -0,0 +1,251 @@
+package order
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+	ErrCartNotFound     = errors.New("Cart not found")
+	ErrCartLineNotFound = errors.New("Cart line not found")
+	ErrInvalidCartLine  = errors.New("Invalid cart line")
+	ErrEmptyCart        = errors.New("Cart is empty")
+)
+
+const (
+	// Carts expire when left untouched this long.
+	guestCartTTL   = 7 * 24 * time.Hour
+	accountCartTTL = 30 * 24 * time.Hour
+	// maxCartQuantity caps the quantity of one cart line.
+	maxCartQuantity = 1000
+)
+
+// CartOwner names the cart of an account, or the cart of a guest by its
+// token. The account wins when both are set.
+type CartOwner struct {
+	AccountID string `json:"account_id,omitempty"`
+	Token     string `json:"token,omitempty"`
+}
+
+// Cart holds what a customer is about to order. An account has at most one
+// cart, a guest has the one its token names.
+type Cart struct {
+	ID        string     `json:"id"`
+	AccountID string     `json:"account_id,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	Lines     []CartLine `json:"lines"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// CartLine is a product in a cart. Name, Price and Available are looked up
+// in the catalog every time the cart is read, they are never stored.
+type CartLine struct {
+	ProductID string  `json:"product_id"`
+	Quantity  uint64  `json:"quantity"`
+	Name      string  `json:"name,omitempty"`
+	Price     float64 `json:"price,omitempty"`
+	Available bool    `json:"available,omitempty"`
+}
+
+// Total is what the available lines of the cart cost.
+func (c Cart) Total() float64 {
+	total := 0.0
+	for _, l := range c.Lines {
+		if l.Available {
+			total += l.Price * float64(l.Quantity)
+		}
+	}
+	return math.Round(total*100) / 100
+}
+
+func (c Cart) line(productID string) int {
+	for i, l := range c.Lines {
+		if l.ProductID == productID {
+			return i
+		}
+	}
+	return -1
+}
+
+func newCartToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// cart returns the cart of owner, an empty unsaved one when it has none. An
+// expired cart comes back empty.
+func (s *orderService) cart(ctx context.Context, owner CartOwner) (*Cart, error) {
+	if owner.AccountID != "" {
+		owner.Token = ""
+	}
+
+	now := time.Now().UTC()
+	c, err := s.repository.GetCart(ctx, owner)
+	if err == ErrCartNotFound {
+		c = &Cart{ID: ksuid.New().String(), AccountID: owner.AccountID, Token: owner.Token, CreatedAt: now}
+		if owner.AccountID == "" && owner.Token == "" {
+			if c.Token, err = newCartToken(); err != nil {
+				return nil, err
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	} else if !c.ExpiresAt.After(now) {
+		c.Lines = nil
+	}
+	if c.Lines == nil {
+		c.Lines = []CartLine{}
+	}
+	return c, nil
+}
+
+func (s *orderService) saveCart(ctx context.Context, c *Cart) error {
+	c.UpdatedAt = time.Now().UTC()
+	ttl := guestCartTTL
+	if c.AccountID != "" {
+		ttl = accountCartTTL
+	}
+	c.ExpiresAt = c.UpdatedAt.Add(ttl)
+	return s.repository.PutCart(ctx, *c)
+}
+
+// GetCart returns the cart of owner. A guest without a token gets an empty
+// cart with a new token, the cart is stored once something is added.
+func (s *orderService) GetCart(ctx context.Context, owner CartOwner) (*Cart, error) {
+	return s.cart(ctx, owner)
+}
+
+// AddCartLine adds quantity of a product to the cart, on top of what it
+// already holds of it.
+func (s *orderService) AddCartLine(ctx context.Context, owner CartOwner, productID string, quantity uint64) (*Cart, error) {
+	if productID == "" || quantity == 0 {
+		return nil, ErrInvalidCartLine
+	}
+
+	c, err := s.cart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if i := c.line(productID); i >= 0 {
+		quantity += c.Lines[i].Quantity
+		c.Lines[i].Quantity = quantity
+	} else {
+		c.Lines = append(c.Lines, CartLine{ProductID: productID, Quantity: quantity})
+	}
+	if quantity > maxCartQuantity {
+		return nil, ErrInvalidCartLine
+	}
+
+	if err := s.saveCart(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UpdateCartLine sets the quantity of a product already in the cart, zero
+// removes it.
+func (s *orderService) UpdateCartLine(ctx context.Context, owner CartOwner, productID string, quantity uint64) (*Cart, error) {
+	if quantity > maxCartQuantity {
+		return nil, ErrInvalidCartLine
+	}
+
+	c, err := s.cart(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	i := c.line(productID)
+	if i < 0 {
+		return nil, ErrCartLineNotFound
+	}
+	if quantity == 0 {
+		c.Lines = append(c.Lines[:i], c.Lines[i+1:]...)
+	} else {
+		c.Lines[i].Quantity = quantity
+	}
+
+	if err := s.saveCart(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *orderService) RemoveCartLine(ctx context.Context, owner CartOwner, productID string) (*Cart, error) {
+	return s.UpdateCartLine(ctx, owner, productID, 0)
+}
+
+// MergeCart moves the cart of a guest into the cart of the account it
+// logged in as, adding up quantities of the products in both.
+func (s *orderService) MergeCart(ctx context.Context, token string, accountID string) (*Cart, error) {
+	if token == "" || accountID == "" {
+		return nil, ErrCartNotFound
+	}
+
+	c, err := s.cart(ctx, CartOwner{AccountID: accountID})
+	if err != nil {
+		return nil, err
+	}
+	guest, err := s.cart(ctx, CartOwner{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	if len(guest.Lines) == 0 {
+		return c, nil
+	}
+
+	for _, l := range guest.Lines {
+		if i := c.line(l.ProductID); i >= 0 {
+			c.Lines[i].Quantity += l.Quantity
+			if c.Lines[i].Quantity > maxCartQuantity {
+				c.Lines[i].Quantity = maxCartQuantity
+			}
+		} else {
+			c.Lines = append(c.Lines, CartLine{ProductID: l.ProductID, Quantity: l.Quantity})
+		}
+	}
+
+	if err := s.saveCart(ctx, c); err != nil {
+		return nil, err
+	}
+	if err := s.repository.DeleteCart(ctx, guest.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ClearCart empties a cart, once it was checked out.
+func (s *orderService) ClearCart(ctx context.Context, id string) error {
+	return s.repository.DeleteCart(ctx, id)
+}
+
+// RunCartCleanup deletes expired carts every interval until ctx is done.
+func (s *orderService) RunCartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.repository.DeleteExpiredCarts(ctx, time.Now().UTC())
+		if err != nil {
+			log.Println("Error deleting expired carts: ", err)
+		} else if n > 0 {
+			log.Printf("Deleted %d expired carts", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -28,7 +28,9 @@ func (c *Client) Close() {
 	c.conn.Close()
 }
 
-func (c *Client) PostOrder(ctx context.Context, accountID string, products []OrderedProduct) (*Order, error) {
+// PostOrder places an order for the products at their catalog prices, with
+// the coupons applied and taxed for address.
+func (c *Client) PostOrder(ctx context.Context, accountID string, products []OrderedProduct, coupons []string, address Address) (*Order, error) {
 	postProducts := []*pb.PostOrderRequest_OrderProduct{}
 	for _, p := range products {
 		postProducts = append(postProducts, &pb.PostOrderRequest_OrderProduct{
@@ -39,57 +41,433 @@ func (c *Client) PostOrder(ctx context.Context, accountID string, products []Ord
 	r, err := c.service.PostOrder(ctx, &pb.PostOrderRequest{
 		AccountId: accountID,
 		Products:  postProducts,
+		Coupons:   coupons,
+		ShippingAddress: &pb.Address{
+			Country: address.Country,
+			Region:  address.Region,
+		},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	newOrder := r.Order
-	newOrderCreatedAt := time.Time{}
-	if err := newOrderCreatedAt.UnmarshalBinary(newOrder.CreatedAt); err != nil {
+	return orderFromProto(r.Order)
+}
+
+func (c *Client) GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error) {
+	r, err := c.service.GetOrderForAccount(ctx, &pb.GetOrderForAccountRequest{AccountId: accountID})
+	if err != nil {
+		log.Println("Error getting order: ", err)
 		return nil, err
 	}
 
-	return &Order{
-		ID:         newOrder.Id,
-		CreatedAt:  newOrderCreatedAt,
-		AccountID:  newOrder.AccountId,
-		TotalPrice: newOrder.TotalPrice,
-		Products:   products,
-	}, nil
+	orders := []Order{}
+	for _, orderProto := range r.Orders {
+		newOrder, err := orderFromProto(orderProto)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *newOrder)
+	}
+	return orders, nil
+}
+
+func (c *Client) GetOrder(ctx context.Context, id string) (*Order, error) {
+	r, err := c.service.GetOrder(ctx, &pb.GetOrderRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
 
+	return orderFromProto(r.Order)
 }
 
-func (c *Client) GetOrdersForAccount(ctx context.Context, accountID string) ([]Order, error) {
-	r, err := c.service.GetOrderForAccount(ctx, &pb.GetOrderForAccountRequest{AccountId: accountID})
+// ListOrders pages through the orders matching filter, newest first.
+func (c *Client) ListOrders(ctx context.Context, filter OrderFilter, skip uint64, take uint64) ([]Order, error) {
+	req := &pb.ListOrdersRequest{
+		Skip:      skip,
+		Take:      take,
+		AccountId: filter.AccountID,
+		MinTotal:  filter.MinTotal,
+		MaxTotal:  filter.MaxTotal,
+		ProductId: filter.ProductID,
+	}
+	for _, status := range filter.Statuses {
+		req.Statuses = append(req.Statuses, string(status))
+	}
+	var err error
+	if req.CreatedAfter, err = optionalTimeToProto(filter.CreatedAfter); err != nil {
+		return nil, err
+	}
+	if req.CreatedBefore, err = optionalTimeToProto(filter.CreatedBefore); err != nil {
+		return nil, err
+	}
+
+	r, err := c.service.ListOrders(ctx, req)
 	if err != nil {
-		log.Fatal("Error getting order: ", err)
 		return nil, err
 	}
 
 	orders := []Order{}
 	for _, orderProto := range r.Orders {
-		newOrder := Order{
-			ID:         orderProto.Id,
-			AccountID:  orderProto.AccountId,
-			TotalPrice: orderProto.TotalPrice,
-		}
-		newOrder.CreatedAt = time.Time{}
-		newOrder.CreatedAt.UnmarshalBinary(orderProto.CreatedAt)
-
-		products := []OrderedProduct{}
-		for _, productProto := range orderProto.Products {
-
-			products = append(products, OrderedProduct{
-				ID:          productProto.Id,
-				Name:        productProto.Name,
-				Description: productProto.Description,
-				Price:       productProto.Price,
-				Quantity:    productProto.Quantity,
-			})
+		o, err := orderFromProto(orderProto)
+		if err != nil {
+			return nil, err
 		}
-		newOrder.Products = products
-		orders = append(orders, newOrder)
+		orders = append(orders, *o)
 	}
 	return orders, nil
 }
+
+func optionalTimeToProto(t *time.Time) ([]byte, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return t.MarshalBinary()
+}
+
+// UpdateOrderStatus moves an order to status on behalf of actor.
+func (c *Client) UpdateOrderStatus(ctx context.Context, id string, status OrderStatus, actor string) (*Order, error) {
+	r, err := c.service.UpdateOrderStatus(ctx, &pb.UpdateOrderStatusRequest{
+		Id:     id,
+		Status: string(status),
+		Actor:  actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderFromProto(r.Order)
+}
+
+// CancelOrder cancels an order, refunding it if it was paid. A non-empty
+// accountID makes the call fail unless the order belongs to that account.
+func (c *Client) CancelOrder(ctx context.Context, id string, accountID string, actor string, reason string) (*Order, error) {
+	r, err := c.service.CancelOrder(ctx, &pb.CancelOrderRequest{
+		Id:        id,
+		AccountId: accountID,
+		Actor:     actor,
+		Reason:    reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderFromProto(r.Order)
+}
+
+// RefundOrder refunds quantities of some lines, or everything left when
+// lines is empty. Line amounts are ignored, they follow from the order.
+func (c *Client) RefundOrder(ctx context.Context, id string, lines []RefundLine, actor string, reason string) (*Order, error) {
+	req := &pb.RefundOrderRequest{Id: id, Actor: actor, Reason: reason}
+	for _, l := range lines {
+		req.Lines = append(req.Lines, &pb.RefundOrderRequest_Line{ProductId: l.ProductID, Quantity: l.Quantity})
+	}
+
+	r, err := c.service.RefundOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderFromProto(r.Order)
+}
+
+func orderFromProto(o *pb.Order) (*Order, error) {
+	newOrder := &Order{
+		ID:         o.Id,
+		AccountID:  o.AccountId,
+		TotalPrice: o.TotalPrice,
+		Products:   []OrderedProduct{},
+		Status:     OrderStatus(o.Status),
+		History:    []StatusChange{},
+		Refunds:    []Refund{},
+		Discounts:  []Discount{},
+		TaxMode:    TaxMode(o.TaxMode),
+		TaxLines:   []TaxLine{},
+	}
+	if o.ShippingAddress != nil {
+		newOrder.Address = Address{Country: o.ShippingAddress.Country, Region: o.ShippingAddress.Region}
+	}
+	for _, t := range o.TaxLines {
+		newOrder.TaxLines = append(newOrder.TaxLines, TaxLine{
+			ProductID:    t.ProductId,
+			TaxClass:     t.TaxClass,
+			Jurisdiction: t.Jurisdiction,
+			Rate:         t.Rate,
+			Taxable:      t.Taxable,
+			Amount:       t.Amount,
+		})
+	}
+	for _, d := range o.Discounts {
+		newOrder.Discounts = append(newOrder.Discounts, Discount{ProductID: d.ProductId, Code: d.Code, Amount: d.Amount})
+	}
+	if err := newOrder.CreatedAt.UnmarshalBinary(o.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	for _, p := range o.Products {
+		newOrder.Products = append(newOrder.Products, orderedProductFromProto(p))
+	}
+	for _, r := range o.Refunds {
+		refund := Refund{
+			ID:      r.Id,
+			OrderID: o.Id,
+			Amount:  r.Amount,
+			Reason:  r.Reason,
+			Actor:   r.Actor,
+			Lines:   []RefundLine{},
+		}
+		if err := refund.CreatedAt.UnmarshalBinary(r.CreatedAt); err != nil {
+			return nil, err
+		}
+		for _, l := range r.Lines {
+			refund.Lines = append(refund.Lines, RefundLine{ProductID: l.ProductId, Quantity: l.Quantity, Amount: l.Amount})
+		}
+		newOrder.Refunds = append(newOrder.Refunds, refund)
+	}
+	for _, c := range o.History {
+		change := StatusChange{From: OrderStatus(c.From), To: OrderStatus(c.To), Actor: c.Actor}
+		if err := change.CreatedAt.UnmarshalBinary(c.CreatedAt); err != nil {
+			return nil, err
+		}
+		newOrder.History = append(newOrder.History, change)
+	}
+	return newOrder, nil
+}
+
+func orderedProductFromProto(p *pb.Order_OrderedProduct) OrderedProduct {
+	product := OrderedProduct{
+		ID:          p.Id,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Quantity:    p.Quantity,
+		Currency:    p.Currency,
+		ImageURL:    p.ImageUrl,
+	}
+	for _, c := range p.Components {
+		product.Components = append(product.Components, OrderedComponent{ProductID: c.ProductId, Quantity: c.Quantity})
+	}
+	return product
+}
+
+// HasPurchased reports whether an order of the account contains the
+// product, on its own or as part of a bundle. Cancelled and refunded
+// orders do not count.
+func (c *Client) HasPurchased(ctx context.Context, accountID string, productID string) (bool, error) {
+	if accountID == "" || productID == "" {
+		return false, nil
+	}
+
+	orders, err := c.ListOrders(ctx, OrderFilter{
+		AccountID: accountID,
+		ProductID: productID,
+		Statuses:  []OrderStatus{StatusPending, StatusPaid, StatusFulfilled, StatusShipped, StatusDelivered},
+	}, 0, 1)
+	if err != nil {
+		return false, err
+	}
+	return len(orders) > 0, nil
+}
+
+// CoPurchasedProducts returns, for the products most often ordered together
+// with productID, the number of orders they share.
+func (c *Client) CoPurchasedProducts(ctx context.Context, productID string, limit uint64) (map[string]uint64, error) {
+	r, err := c.service.GetCoPurchasedProducts(ctx, &pb.GetCoPurchasedProductsRequest{
+		ProductId: productID,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]uint64{}
+	for _, p := range r.Products {
+		counts[p.ProductId] = p.Count
+	}
+	return counts, nil
+}
+
+// OrderedProducts pages through the ids of every product that was ever
+// ordered, starting after the given id.
+func (c *Client) OrderedProducts(ctx context.Context, after string, take uint64) ([]ProductReference, error) {
+	r, err := c.service.GetOrderedProducts(ctx, &pb.GetOrderedProductsRequest{
+		After: after,
+		Take:  take,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	products := []ProductReference{}
+	for _, p := range r.Products {
+		products = append(products, ProductReference{
+			ProductID:   p.ProductId,
+			Orders:      p.Orders,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price,
+		})
+	}
+	return products, nil
+}
+
+// GetOrderSaga returns how placing an order went, step by step.
+func (c *Client) GetOrderSaga(ctx context.Context, id string) (*OrderSaga, error) {
+	r, err := c.service.GetOrderSaga(ctx, &pb.GetOrderSagaRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := orderFromProto(r.Saga.Order)
+	if err != nil {
+		return nil, err
+	}
+	saga := &OrderSaga{
+		ID:              r.Saga.Id,
+		Status:          SagaStatus(r.Saga.Status),
+		Order:           *o,
+		Steps:           []SagaStep{},
+		AuthorizationID: r.Saga.AuthorizationId,
+		Error:           r.Saga.Error,
+	}
+	if err := saga.CreatedAt.UnmarshalBinary(r.Saga.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := saga.UpdatedAt.UnmarshalBinary(r.Saga.UpdatedAt); err != nil {
+		return nil, err
+	}
+	for _, s := range r.Saga.Steps {
+		step := SagaStep{Name: s.Name, Status: StepStatus(s.Status), Error: s.Error}
+		if err := step.UpdatedAt.UnmarshalBinary(s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		saga.Steps = append(saga.Steps, step)
+	}
+	return saga, nil
+}
+
+func cartFromProto(c *pb.Cart) (*Cart, error) {
+	cart := &Cart{
+		ID:        c.Id,
+		AccountID: c.AccountId,
+		Token:     c.Token,
+		Lines:     []CartLine{},
+	}
+	if err := cart.CreatedAt.UnmarshalBinary(c.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := cart.UpdatedAt.UnmarshalBinary(c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := cart.ExpiresAt.UnmarshalBinary(c.ExpiresAt); err != nil {
+		return nil, err
+	}
+	for _, l := range c.Lines {
+		cart.Lines = append(cart.Lines, CartLine{
+			ProductID: l.ProductId,
+			Quantity:  l.Quantity,
+			Name:      l.Name,
+			Price:     l.Price,
+			Available: l.Available,
+		})
+	}
+	return cart, nil
+}
+
+// GetCart returns the cart of owner priced at current catalog prices. A
+// guest without a token gets an empty cart with a new token.
+func (c *Client) GetCart(ctx context.Context, owner CartOwner) (*Cart, error) {
+	r, err := c.service.GetCart(ctx, &pb.GetCartRequest{AccountId: owner.AccountID, Token: owner.Token})
+	if err != nil {
+		return nil, err
+	}
+	return cartFromProto(r.Cart)
+}
+
+func (c *Client) AddCartLine(ctx context.Context, owner CartOwner, productID string, quantity uint64) (*Cart, error) {
+	r, err := c.service.AddCartLine(ctx, &pb.AddCartLineRequest{
+		AccountId: owner.AccountID,
+		Token:     owner.Token,
+		ProductId: productID,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cartFromProto(r.Cart)
+}
+
+// UpdateCartLine sets the quantity of a product in the cart, zero removes
+// it.
+func (c *Client) UpdateCartLine(ctx context.Context, owner CartOwner, productID string, quantity uint64) (*Cart, error) {
+	r, err := c.service.UpdateCartLine(ctx, &pb.UpdateCartLineRequest{
+		AccountId: owner.AccountID,
+		Token:     owner.Token,
+		ProductId: productID,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cartFromProto(r.Cart)
+}
+
+func (c *Client) RemoveCartLine(ctx context.Context, owner CartOwner, productID string) (*Cart, error) {
+	r, err := c.service.RemoveCartLine(ctx, &pb.RemoveCartLineRequest{
+		AccountId: owner.AccountID,
+		Token:     owner.Token,
+		ProductId: productID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cartFromProto(r.Cart)
+}
+
+// MergeCart moves the cart of a guest into the cart of the account it
+// logged in as.
+func (c *Client) MergeCart(ctx context.Context, token string, accountID string) (*Cart, error) {
+	r, err := c.service.MergeCart(ctx, &pb.MergeCartRequest{Token: token, AccountId: accountID})
+	if err != nil {
+		return nil, err
+	}
+	return cartFromProto(r.Cart)
+}
+
+// CheckoutCart orders what is in the cart of an account with the coupons
+// applied and taxed for address, after merging in the guest cart of token
+// when it is set.
+func (c *Client) CheckoutCart(ctx context.Context, accountID string, token string, coupons []string, address Address) (*Order, error) {
+	r, err := c.service.CheckoutCart(ctx, &pb.CheckoutCartRequest{
+		AccountId:       accountID,
+		Token:           token,
+		Coupons:         coupons,
+		ShippingAddress: &pb.Address{Country: address.Country, Region: address.Region},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderFromProto(r.Order)
+}
+
+// CreatePromotion adds a coupon code. The code is matched ignoring case.
+func (c *Client) CreatePromotion(ctx context.Context, p Promotion) (*Promotion, error) {
+	promotion, err := promotionToProto(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.service.CreatePromotion(ctx, &pb.CreatePromotionRequest{Promotion: promotion})
+	if err != nil {
+		return nil, err
+	}
+	return promotionFromProto(r.Promotion)
+}
+
+func (c *Client) GetPromotion(ctx context.Context, code string) (*Promotion, error) {
+	r, err := c.service.GetPromotion(ctx, &pb.GetPromotionRequest{Code: code})
+	if err != nil {
+		return nil, err
+	}
+	return promotionFromProto(r.Promotion)
+}
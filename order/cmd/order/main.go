@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/timothydzokoto/grpc_graphql_microservice/account"
 	"github.com/timothydzokoto/grpc_graphql_microservice/order"
 	"github.com/tinrab/retry"
 )
@@ -13,6 +15,19 @@ type Config struct {
 	DatbaseURL string `envconfig:"DATABASE_URL"`
 	AccountUrl string `envconfig:"ACCOUNT_SERVICE_URL"`
 	CatalogUrl string `envconfig:"CATALOG_SERVICE_URL"`
+	// SagaRecoveryInterval is how often orders left halfway placed are
+	// finished or undone.
+	SagaRecoveryInterval time.Duration `envconfig:"SAGA_RECOVERY_INTERVAL" default:"1m"`
+	// OutboxFile is where order events are published. When empty they go
+	// to an in-process broker that logs them.
+	OutboxFile     string        `envconfig:"OUTBOX_FILE"`
+	OutboxInterval time.Duration `envconfig:"OUTBOX_INTERVAL" default:"5s"`
+	// CartCleanupInterval is how often expired carts are deleted.
+	CartCleanupInterval time.Duration `envconfig:"CART_CLEANUP_INTERVAL" default:"1h"`
+	// TaxTable is a JSON file of tax rates, orders are not taxed without
+	// one. TaxMode is exclusive or inclusive.
+	TaxTable string `envconfig:"TAX_TABLE"`
+	TaxMode  string `envconfig:"TAX_MODE" default:"exclusive"`
 }
 
 func main() {
@@ -31,9 +46,58 @@ func main() {
 	})
 
 	defer r.Close()
+
+	var inv order.Inventory
+	retry.ForeverSleep(2*time.Second, func(_ int) (err error) {
+		inv, err = order.NewPostgresInventory(cfg.DatbaseURL)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	})
+
+	accountClient, err := account.NewClient(cfg.AccountUrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer accountClient.Close()
+
+	taxMode := order.TaxMode(cfg.TaxMode)
+	if taxMode != order.TaxExclusive && taxMode != order.TaxInclusive {
+		log.Fatalf("Unknown tax mode %q", cfg.TaxMode)
+	}
+	var tax order.TaxCalculator
+	if cfg.TaxTable != "" {
+		table, err := order.LoadTaxTable(cfg.TaxTable)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tax = table
+	}
+
 	log.Println("Listening on port 8080....")
 
-	s := order.NewService(r)
-	log.Fatal(order.ListenGRPC(s, cfg.AccountUrl, cfg.CatalogUrl, 8080))
+	s := order.NewService(r, inv, accountClient, order.DeferredPayments{}, tax, taxMode)
+	go s.RunSagaRecovery(context.Background(), cfg.SagaRecoveryInterval)
+	go s.RunCartCleanup(context.Background(), cfg.CartCleanupInterval)
+
+	var publisher order.Publisher
+	if cfg.OutboxFile != "" {
+		filePublisher, err := order.NewFilePublisher(cfg.OutboxFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer filePublisher.Close()
+		publisher = filePublisher
+	} else {
+		broker := order.NewBroker()
+		broker.Subscribe(func(ctx context.Context, e order.Event) error {
+			log.Printf("Order event %d: %s %s", e.ID, e.Type, e.OrderID)
+			return nil
+		})
+		publisher = broker
+	}
+	go order.RunOutboxRelay(context.Background(), r, publisher, cfg.OutboxInterval)
+	log.Fatal(order.ListenGRPC(s, cfg.CatalogUrl, 8080))
 
 }
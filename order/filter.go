@@ -0,0 +1,83 @@
+package order
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrInvalidFilter = errors.New("Invalid order filter")
+
+// OrderFilter narrows a list of orders, every set field must match. The
+// total bounds are inclusive and apply to the total before refunds, the
+// created bounds include CreatedAfter and exclude CreatedBefore.
+type OrderFilter struct {
+	AccountID     string        `json:"account_id,omitempty"`
+	Statuses      []OrderStatus `json:"statuses,omitempty"`
+	CreatedAfter  *time.Time    `json:"created_after,omitempty"`
+	CreatedBefore *time.Time    `json:"created_before,omitempty"`
+	MinTotal      *float64      `json:"min_total,omitempty"`
+	MaxTotal      *float64      `json:"max_total,omitempty"`
+	// ProductID matches orders containing the product as a line or as part
+	// of a bundle.
+	ProductID string `json:"product_id,omitempty"`
+}
+
+func (f OrderFilter) validate() error {
+	for _, s := range f.Statuses {
+		if !s.valid() {
+			return ErrInvalidStatus
+		}
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedBefore.After(*f.CreatedAfter) {
+		return ErrInvalidFilter
+	}
+	if f.MinTotal != nil && f.MaxTotal != nil && *f.MaxTotal < *f.MinTotal {
+		return ErrInvalidFilter
+	}
+	return nil
+}
+
+// where returns the SQL condition on orders aliased o, with the arguments
+// it refers to.
+func (f OrderFilter) where() (string, []interface{}) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if f.AccountID != "" {
+		conds = append(conds, "o.account_id = "+arg(f.AccountID))
+	}
+	if len(f.Statuses) > 0 {
+		statuses := []string{}
+		for _, s := range f.Statuses {
+			statuses = append(statuses, string(s))
+		}
+		conds = append(conds, "o.status = ANY("+arg(pq.Array(statuses))+")")
+	}
+	if f.CreatedAfter != nil {
+		conds = append(conds, "o.created_at >= "+arg(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		conds = append(conds, "o.created_at < "+arg(*f.CreatedBefore))
+	}
+	if f.MinTotal != nil {
+		conds = append(conds, "o.price::numeric >= "+arg(*f.MinTotal))
+	}
+	if f.MaxTotal != nil {
+		conds = append(conds, "o.price::numeric <= "+arg(*f.MaxTotal))
+	}
+	if f.ProductID != "" {
+		p := arg(f.ProductID)
+		conds = append(conds, "(EXISTS (SELECT 1 FROM order_products op WHERE op.order_id = o.id AND op.product_id = "+p+")"+
+			" OR EXISTS (SELECT 1 FROM order_product_components c WHERE c.order_id = o.id AND c.product_id = "+p+"))")
+	}
+
+	return strings.Join(conds, " AND "), args
+}
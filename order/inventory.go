@@ -0,0 +1,148 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrOutOfStock = errors.New("Not enough stock")
+
+// Inventory holds stock for the products orders ship. Orders work without
+// one, stock is then not tracked. Both calls must be safe to repeat for
+// the same order.
+type Inventory interface {
+	Reserve(ctx context.Context, orderID string, lines []StockLine) error
+	Release(ctx context.Context, orderID string, lines []StockLine) error
+}
+
+// postgresInventory tracks stock for the products that have a row in the
+// stock table, every other product is always available.
+type postgresInventory struct {
+	db *sql.DB
+}
+
+func NewPostgresInventory(url string) (Inventory, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &postgresInventory{db}, nil
+}
+
+// Reserve takes all lines out of stock or none of them. An order that
+// already holds reservations is left as it is.
+func (inv *postgresInventory) Reserve(ctx context.Context, orderID string, lines []StockLine) (err error) {
+	tx, err := inv.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	reserved := false
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM stock_reservations WHERE order_id = $1)", orderID).Scan(&reserved)
+	if err != nil || reserved {
+		return err
+	}
+
+	// Lines come ordered by product id, so concurrent reservations lock
+	// stock rows in the same order.
+	for _, l := range lines {
+		res, err := tx.ExecContext(ctx,
+			"UPDATE stock SET available = available - $2 WHERE product_id = $1 AND available >= $2",
+			l.ProductID, l.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			tracked := false
+			err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM stock WHERE product_id = $1)", l.ProductID).Scan(&tracked)
+			if err != nil {
+				return err
+			}
+			if tracked {
+				return fmt.Errorf("%w for %s", ErrOutOfStock, l.ProductID)
+			}
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO stock_reservations(order_id, product_id, quantity) VALUES($1, $2, $3)",
+			orderID, l.ProductID, l.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release puts back up to the given quantities of what the order reserved.
+func (inv *postgresInventory) Release(ctx context.Context, orderID string, lines []StockLine) (err error) {
+	tx, err := inv.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	for _, l := range lines {
+		var reserved uint64
+		err = tx.QueryRowContext(ctx,
+			"SELECT quantity FROM stock_reservations WHERE order_id = $1 AND product_id = $2 FOR UPDATE",
+			orderID, l.ProductID,
+		).Scan(&reserved)
+		if err == sql.ErrNoRows {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		quantity := l.Quantity
+		if quantity > reserved {
+			quantity = reserved
+		}
+		if _, err = tx.ExecContext(ctx, "UPDATE stock SET available = available + $2 WHERE product_id = $1", l.ProductID, quantity); err != nil {
+			return err
+		}
+		if quantity == reserved {
+			_, err = tx.ExecContext(ctx, "DELETE FROM stock_reservations WHERE order_id = $1 AND product_id = $2", orderID, l.ProductID)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				"UPDATE stock_reservations SET quantity = quantity - $3 WHERE order_id = $1 AND product_id = $2",
+				orderID, l.ProductID, quantity,
+			)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
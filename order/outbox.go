@@ -0,0 +1,142 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventOrderPlaced        EventType = "OrderPlaced"
+	EventOrderStatusChanged EventType = "OrderStatusChanged"
+	EventOrderCancelled     EventType = "OrderCancelled"
+)
+
+// Event is something that happened to an order, written to the outbox in
+// the same transaction as the change itself. Events of an order are
+// published in the order they happened, at least once, consumers tell
+// repeats apart by id.
+type Event struct {
+	ID        uint64          `json:"id"`
+	OrderID   string          `json:"order_id"`
+	Type      EventType       `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// StatusChangeEvent is the payload of OrderStatusChanged and
+// OrderCancelled. OrderPlaced carries the whole order.
+type StatusChangeEvent struct {
+	OrderID string `json:"order_id"`
+	StatusChange
+}
+
+// Publisher delivers events to whoever listens to them.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// retryDelay is how long a failed event waits before it is published
+// again, doubling with every attempt up to an hour.
+func retryDelay(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// RunOutboxRelay publishes the events waiting in the outbox every interval
+// until ctx is done. An event that fails holds back the later events of its
+// order until it is published.
+func RunOutboxRelay(ctx context.Context, r Repository, p Publisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.RelayEvents(ctx, 100, p.Publish)
+			if err != nil {
+				log.Println("Error relaying order events: ", err)
+			}
+			if err != nil || n < 100 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FilePublisher appends events to a file, one JSON object per line.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err = p.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// Broker hands events to the handlers subscribed in the same process. An
+// event is published again when any handler fails, so handlers must
+// tolerate repeats.
+type Broker struct {
+	mu       sync.RWMutex
+	handlers []func(ctx context.Context, e Event) error
+}
+
+func NewBroker() *Broker {
+	return &Broker{}
+}
+
+func (b *Broker) Subscribe(handler func(ctx context.Context, e Event) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Broker) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		if err := handler(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
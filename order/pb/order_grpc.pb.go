@@ -19,8 +19,24 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	OrderService_PostOrder_FullMethodName          = "/order.OrderService/PostOrder"
-	OrderService_GetOrderForAccount_FullMethodName = "/order.OrderService/GetOrderForAccount"
+	OrderService_PostOrder_FullMethodName              = "/order.OrderService/PostOrder"
+	OrderService_GetOrderForAccount_FullMethodName     = "/order.OrderService/GetOrderForAccount"
+	OrderService_GetOrder_FullMethodName               = "/order.OrderService/GetOrder"
+	OrderService_ListOrders_FullMethodName             = "/order.OrderService/ListOrders"
+	OrderService_GetCoPurchasedProducts_FullMethodName = "/order.OrderService/GetCoPurchasedProducts"
+	OrderService_GetOrderedProducts_FullMethodName     = "/order.OrderService/GetOrderedProducts"
+	OrderService_UpdateOrderStatus_FullMethodName      = "/order.OrderService/UpdateOrderStatus"
+	OrderService_CancelOrder_FullMethodName            = "/order.OrderService/CancelOrder"
+	OrderService_RefundOrder_FullMethodName            = "/order.OrderService/RefundOrder"
+	OrderService_GetOrderSaga_FullMethodName           = "/order.OrderService/GetOrderSaga"
+	OrderService_GetCart_FullMethodName                = "/order.OrderService/GetCart"
+	OrderService_AddCartLine_FullMethodName            = "/order.OrderService/AddCartLine"
+	OrderService_UpdateCartLine_FullMethodName         = "/order.OrderService/UpdateCartLine"
+	OrderService_RemoveCartLine_FullMethodName         = "/order.OrderService/RemoveCartLine"
+	OrderService_MergeCart_FullMethodName              = "/order.OrderService/MergeCart"
+	OrderService_CheckoutCart_FullMethodName           = "/order.OrderService/CheckoutCart"
+	OrderService_CreatePromotion_FullMethodName        = "/order.OrderService/CreatePromotion"
+	OrderService_GetPromotion_FullMethodName           = "/order.OrderService/GetPromotion"
 )
 
 // OrderServiceClient is the client API for OrderService service.
@@ -29,6 +45,22 @@ const (
 type OrderServiceClient interface {
 	PostOrder(ctx context.Context, in *PostOrderRequest, opts ...grpc.CallOption) (*PostOrderResponse, error)
 	GetOrderForAccount(ctx context.Context, in *GetOrderForAccountRequest, opts ...grpc.CallOption) (*GetOrderForAccountResponse, error)
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*GetOrderResponse, error)
+	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
+	GetCoPurchasedProducts(ctx context.Context, in *GetCoPurchasedProductsRequest, opts ...grpc.CallOption) (*GetCoPurchasedProductsResponse, error)
+	GetOrderedProducts(ctx context.Context, in *GetOrderedProductsRequest, opts ...grpc.CallOption) (*GetOrderedProductsResponse, error)
+	UpdateOrderStatus(ctx context.Context, in *UpdateOrderStatusRequest, opts ...grpc.CallOption) (*UpdateOrderStatusResponse, error)
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	RefundOrder(ctx context.Context, in *RefundOrderRequest, opts ...grpc.CallOption) (*RefundOrderResponse, error)
+	GetOrderSaga(ctx context.Context, in *GetOrderSagaRequest, opts ...grpc.CallOption) (*GetOrderSagaResponse, error)
+	GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*GetCartResponse, error)
+	AddCartLine(ctx context.Context, in *AddCartLineRequest, opts ...grpc.CallOption) (*AddCartLineResponse, error)
+	UpdateCartLine(ctx context.Context, in *UpdateCartLineRequest, opts ...grpc.CallOption) (*UpdateCartLineResponse, error)
+	RemoveCartLine(ctx context.Context, in *RemoveCartLineRequest, opts ...grpc.CallOption) (*RemoveCartLineResponse, error)
+	MergeCart(ctx context.Context, in *MergeCartRequest, opts ...grpc.CallOption) (*MergeCartResponse, error)
+	CheckoutCart(ctx context.Context, in *CheckoutCartRequest, opts ...grpc.CallOption) (*CheckoutCartResponse, error)
+	CreatePromotion(ctx context.Context, in *CreatePromotionRequest, opts ...grpc.CallOption) (*CreatePromotionResponse, error)
+	GetPromotion(ctx context.Context, in *GetPromotionRequest, opts ...grpc.CallOption) (*GetPromotionResponse, error)
 }
 
 type orderServiceClient struct {
@@ -59,12 +91,188 @@ func (c *orderServiceClient) GetOrderForAccount(ctx context.Context, in *GetOrde
 	return out, nil
 }
 
+func (c *orderServiceClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*GetOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListOrdersResponse)
+	err := c.cc.Invoke(ctx, OrderService_ListOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetCoPurchasedProducts(ctx context.Context, in *GetCoPurchasedProductsRequest, opts ...grpc.CallOption) (*GetCoPurchasedProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCoPurchasedProductsResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetCoPurchasedProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetOrderedProducts(ctx context.Context, in *GetOrderedProductsRequest, opts ...grpc.CallOption) (*GetOrderedProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderedProductsResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetOrderedProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) UpdateOrderStatus(ctx context.Context, in *UpdateOrderStatusRequest, opts ...grpc.CallOption) (*UpdateOrderStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateOrderStatusResponse)
+	err := c.cc.Invoke(ctx, OrderService_UpdateOrderStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelOrderResponse)
+	err := c.cc.Invoke(ctx, OrderService_CancelOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) RefundOrder(ctx context.Context, in *RefundOrderRequest, opts ...grpc.CallOption) (*RefundOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefundOrderResponse)
+	err := c.cc.Invoke(ctx, OrderService_RefundOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetOrderSaga(ctx context.Context, in *GetOrderSagaRequest, opts ...grpc.CallOption) (*GetOrderSagaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetOrderSagaResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetOrderSaga_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*GetCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCartResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) AddCartLine(ctx context.Context, in *AddCartLineRequest, opts ...grpc.CallOption) (*AddCartLineResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddCartLineResponse)
+	err := c.cc.Invoke(ctx, OrderService_AddCartLine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) UpdateCartLine(ctx context.Context, in *UpdateCartLineRequest, opts ...grpc.CallOption) (*UpdateCartLineResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateCartLineResponse)
+	err := c.cc.Invoke(ctx, OrderService_UpdateCartLine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) RemoveCartLine(ctx context.Context, in *RemoveCartLineRequest, opts ...grpc.CallOption) (*RemoveCartLineResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveCartLineResponse)
+	err := c.cc.Invoke(ctx, OrderService_RemoveCartLine_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) MergeCart(ctx context.Context, in *MergeCartRequest, opts ...grpc.CallOption) (*MergeCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeCartResponse)
+	err := c.cc.Invoke(ctx, OrderService_MergeCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CheckoutCart(ctx context.Context, in *CheckoutCartRequest, opts ...grpc.CallOption) (*CheckoutCartResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckoutCartResponse)
+	err := c.cc.Invoke(ctx, OrderService_CheckoutCart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) CreatePromotion(ctx context.Context, in *CreatePromotionRequest, opts ...grpc.CallOption) (*CreatePromotionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreatePromotionResponse)
+	err := c.cc.Invoke(ctx, OrderService_CreatePromotion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orderServiceClient) GetPromotion(ctx context.Context, in *GetPromotionRequest, opts ...grpc.CallOption) (*GetPromotionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPromotionResponse)
+	err := c.cc.Invoke(ctx, OrderService_GetPromotion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrderServiceServer is the server API for OrderService service.
 // All implementations must embed UnimplementedOrderServiceServer
 // for forward compatibility.
 type OrderServiceServer interface {
 	PostOrder(context.Context, *PostOrderRequest) (*PostOrderResponse, error)
 	GetOrderForAccount(context.Context, *GetOrderForAccountRequest) (*GetOrderForAccountResponse, error)
+	GetOrder(context.Context, *GetOrderRequest) (*GetOrderResponse, error)
+	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
+	GetCoPurchasedProducts(context.Context, *GetCoPurchasedProductsRequest) (*GetCoPurchasedProductsResponse, error)
+	GetOrderedProducts(context.Context, *GetOrderedProductsRequest) (*GetOrderedProductsResponse, error)
+	UpdateOrderStatus(context.Context, *UpdateOrderStatusRequest) (*UpdateOrderStatusResponse, error)
+	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	RefundOrder(context.Context, *RefundOrderRequest) (*RefundOrderResponse, error)
+	GetOrderSaga(context.Context, *GetOrderSagaRequest) (*GetOrderSagaResponse, error)
+	GetCart(context.Context, *GetCartRequest) (*GetCartResponse, error)
+	AddCartLine(context.Context, *AddCartLineRequest) (*AddCartLineResponse, error)
+	UpdateCartLine(context.Context, *UpdateCartLineRequest) (*UpdateCartLineResponse, error)
+	RemoveCartLine(context.Context, *RemoveCartLineRequest) (*RemoveCartLineResponse, error)
+	MergeCart(context.Context, *MergeCartRequest) (*MergeCartResponse, error)
+	CheckoutCart(context.Context, *CheckoutCartRequest) (*CheckoutCartResponse, error)
+	CreatePromotion(context.Context, *CreatePromotionRequest) (*CreatePromotionResponse, error)
+	GetPromotion(context.Context, *GetPromotionRequest) (*GetPromotionResponse, error)
 	mustEmbedUnimplementedOrderServiceServer()
 }
 
@@ -81,6 +289,54 @@ func (UnimplementedOrderServiceServer) PostOrder(context.Context, *PostOrderRequ
 func (UnimplementedOrderServiceServer) GetOrderForAccount(context.Context, *GetOrderForAccountRequest) (*GetOrderForAccountResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOrderForAccount not implemented")
 }
+func (UnimplementedOrderServiceServer) GetOrder(context.Context, *GetOrderRequest) (*GetOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrders not implemented")
+}
+func (UnimplementedOrderServiceServer) GetCoPurchasedProducts(context.Context, *GetCoPurchasedProductsRequest) (*GetCoPurchasedProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCoPurchasedProducts not implemented")
+}
+func (UnimplementedOrderServiceServer) GetOrderedProducts(context.Context, *GetOrderedProductsRequest) (*GetOrderedProductsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderedProducts not implemented")
+}
+func (UnimplementedOrderServiceServer) UpdateOrderStatus(context.Context, *UpdateOrderStatusRequest) (*UpdateOrderStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOrderStatus not implemented")
+}
+func (UnimplementedOrderServiceServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) RefundOrder(context.Context, *RefundOrderRequest) (*RefundOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefundOrder not implemented")
+}
+func (UnimplementedOrderServiceServer) GetOrderSaga(context.Context, *GetOrderSagaRequest) (*GetOrderSagaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderSaga not implemented")
+}
+func (UnimplementedOrderServiceServer) GetCart(context.Context, *GetCartRequest) (*GetCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCart not implemented")
+}
+func (UnimplementedOrderServiceServer) AddCartLine(context.Context, *AddCartLineRequest) (*AddCartLineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddCartLine not implemented")
+}
+func (UnimplementedOrderServiceServer) UpdateCartLine(context.Context, *UpdateCartLineRequest) (*UpdateCartLineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCartLine not implemented")
+}
+func (UnimplementedOrderServiceServer) RemoveCartLine(context.Context, *RemoveCartLineRequest) (*RemoveCartLineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveCartLine not implemented")
+}
+func (UnimplementedOrderServiceServer) MergeCart(context.Context, *MergeCartRequest) (*MergeCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeCart not implemented")
+}
+func (UnimplementedOrderServiceServer) CheckoutCart(context.Context, *CheckoutCartRequest) (*CheckoutCartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckoutCart not implemented")
+}
+func (UnimplementedOrderServiceServer) CreatePromotion(context.Context, *CreatePromotionRequest) (*CreatePromotionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePromotion not implemented")
+}
+func (UnimplementedOrderServiceServer) GetPromotion(context.Context, *GetPromotionRequest) (*GetPromotionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPromotion not implemented")
+}
 func (UnimplementedOrderServiceServer) mustEmbedUnimplementedOrderServiceServer() {}
 func (UnimplementedOrderServiceServer) testEmbeddedByValue()                      {}
 
@@ -138,6 +394,294 @@ func _OrderService_GetOrderForAccount_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrderService_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_ListOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).ListOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_ListOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).ListOrders(ctx, req.(*ListOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetCoPurchasedProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCoPurchasedProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetCoPurchasedProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetCoPurchasedProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetCoPurchasedProducts(ctx, req.(*GetCoPurchasedProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetOrderedProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderedProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrderedProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetOrderedProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrderedProducts(ctx, req.(*GetOrderedProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_UpdateOrderStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOrderStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).UpdateOrderStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_UpdateOrderStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).UpdateOrderStatus(ctx, req.(*UpdateOrderStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_RefundOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).RefundOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_RefundOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).RefundOrder(ctx, req.(*RefundOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetOrderSaga_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderSagaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetOrderSaga(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetOrderSaga_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetOrderSaga(ctx, req.(*GetOrderSagaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetCart(ctx, req.(*GetCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_AddCartLine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddCartLineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).AddCartLine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_AddCartLine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).AddCartLine(ctx, req.(*AddCartLineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_UpdateCartLine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCartLineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).UpdateCartLine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_UpdateCartLine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).UpdateCartLine(ctx, req.(*UpdateCartLineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_RemoveCartLine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveCartLineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).RemoveCartLine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_RemoveCartLine_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).RemoveCartLine(ctx, req.(*RemoveCartLineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_MergeCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).MergeCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_MergeCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).MergeCart(ctx, req.(*MergeCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CheckoutCart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckoutCartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CheckoutCart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CheckoutCart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CheckoutCart(ctx, req.(*CheckoutCartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_CreatePromotion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePromotionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).CreatePromotion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_CreatePromotion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).CreatePromotion(ctx, req.(*CreatePromotionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrderService_GetPromotion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPromotionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderServiceServer).GetPromotion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderService_GetPromotion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderServiceServer).GetPromotion(ctx, req.(*GetPromotionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OrderService_ServiceDesc is the grpc.ServiceDesc for OrderService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +697,70 @@ var OrderService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOrderForAccount",
 			Handler:    _OrderService_GetOrderForAccount_Handler,
 		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _OrderService_GetOrder_Handler,
+		},
+		{
+			MethodName: "ListOrders",
+			Handler:    _OrderService_ListOrders_Handler,
+		},
+		{
+			MethodName: "GetCoPurchasedProducts",
+			Handler:    _OrderService_GetCoPurchasedProducts_Handler,
+		},
+		{
+			MethodName: "GetOrderedProducts",
+			Handler:    _OrderService_GetOrderedProducts_Handler,
+		},
+		{
+			MethodName: "UpdateOrderStatus",
+			Handler:    _OrderService_UpdateOrderStatus_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _OrderService_CancelOrder_Handler,
+		},
+		{
+			MethodName: "RefundOrder",
+			Handler:    _OrderService_RefundOrder_Handler,
+		},
+		{
+			MethodName: "GetOrderSaga",
+			Handler:    _OrderService_GetOrderSaga_Handler,
+		},
+		{
+			MethodName: "GetCart",
+			Handler:    _OrderService_GetCart_Handler,
+		},
+		{
+			MethodName: "AddCartLine",
+			Handler:    _OrderService_AddCartLine_Handler,
+		},
+		{
+			MethodName: "UpdateCartLine",
+			Handler:    _OrderService_UpdateCartLine_Handler,
+		},
+		{
+			MethodName: "RemoveCartLine",
+			Handler:    _OrderService_RemoveCartLine_Handler,
+		},
+		{
+			MethodName: "MergeCart",
+			Handler:    _OrderService_MergeCart_Handler,
+		},
+		{
+			MethodName: "CheckoutCart",
+			Handler:    _OrderService_CheckoutCart_Handler,
+		},
+		{
+			MethodName: "CreatePromotion",
+			Handler:    _OrderService_CreatePromotion_Handler,
+		},
+		{
+			MethodName: "GetPromotion",
+			Handler:    _OrderService_GetPromotion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "order.proto",
@@ -0,0 +1,254 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPromotion    = errors.New("Invalid promotion")
+	ErrCouponNotFound      = errors.New("Coupon not found")
+	ErrCouponNotValid      = errors.New("Coupon is not valid now")
+	ErrCouponLimitReached  = errors.New("Coupon usage limit reached")
+	ErrCouponNotStackable  = errors.New("Coupon cannot be combined with other coupons")
+	ErrCouponNotApplicable = errors.New("Coupon does not apply to the order")
+)
+
+type PromotionKind string
+
+const (
+	// PromotionPercent takes Value percent off the order, or off the lines
+	// of ProductID when set.
+	PromotionPercent PromotionKind = "percent"
+	// PromotionFixed takes Value off the order, or off the lines of
+	// ProductID when set.
+	PromotionFixed PromotionKind = "fixed"
+	// PromotionBuyXGetY makes GetQuantity of every BuyQuantity +
+	// GetQuantity units of ProductID free.
+	PromotionBuyXGetY PromotionKind = "buy_x_get_y"
+)
+
+// Promotion is what a coupon code gives. Zero limits mean unlimited, nil
+// bounds mean open ended.
+type Promotion struct {
+	Code        string        `json:"code"`
+	Kind        PromotionKind `json:"kind"`
+	Value       float64       `json:"value"`
+	ProductID   string        `json:"product_id,omitempty"`
+	BuyQuantity uint64        `json:"buy_quantity,omitempty"`
+	GetQuantity uint64        `json:"get_quantity,omitempty"`
+	// Stackable coupons can be used together, any other coupon is used
+	// alone.
+	Stackable         bool       `json:"stackable"`
+	StartsAt          *time.Time `json:"starts_at,omitempty"`
+	EndsAt            *time.Time `json:"ends_at,omitempty"`
+	MaxUses           uint64     `json:"max_uses"`
+	MaxUsesPerAccount uint64     `json:"max_uses_per_account"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// Discount is what one coupon took off one line of an order.
+type Discount struct {
+	ProductID string  `json:"product_id"`
+	Code      string  `json:"code"`
+	Amount    float64 `json:"amount"`
+}
+
+// NormalizeCouponCode makes codes match however customers type them.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p Promotion) validate() error {
+	if p.Code == "" {
+		return fmt.Errorf("%w: empty code", ErrInvalidPromotion)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: ends before it starts", ErrInvalidPromotion)
+	}
+
+	switch p.Kind {
+	case PromotionPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return fmt.Errorf("%w: percent must be in (0, 100]", ErrInvalidPromotion)
+		}
+	case PromotionFixed:
+		if p.Value <= 0 {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidPromotion)
+		}
+	case PromotionBuyXGetY:
+		if p.ProductID == "" || p.BuyQuantity == 0 || p.GetQuantity == 0 {
+			return fmt.Errorf("%w: buy x get y needs a product and both quantities", ErrInvalidPromotion)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidPromotion, p.Kind)
+	}
+	return nil
+}
+
+func (p Promotion) activeAt(t time.Time) bool {
+	return (p.StartsAt == nil || !t.Before(*p.StartsAt)) && (p.EndsAt == nil || t.Before(*p.EndsAt))
+}
+
+// Subtotal is what the lines of the order cost before discounts.
+func (o Order) Subtotal() float64 {
+	total := 0.0
+	for _, p := range o.Products {
+		total += p.Price * float64(p.Quantity)
+	}
+	return roundCents(total)
+}
+
+// DiscountTotal is what coupons took off the order.
+func (o Order) DiscountTotal() float64 {
+	total := 0.0
+	for _, d := range o.Discounts {
+		total += d.Amount
+	}
+	return roundCents(total)
+}
+
+// lineDiscount is what coupons took off a line.
+func (o Order) lineDiscount(productID string) float64 {
+	total := 0.0
+	for _, d := range o.Discounts {
+		if d.ProductID == productID {
+			total += d.Amount
+		}
+	}
+	return total
+}
+
+// applyPromotions works out the discounts of promotions on lines. Buy x get
+// y promotions go first, then percentages, then fixed amounts, each on what
+// is left of the lines after the ones before. No line goes below zero.
+func applyPromotions(lines []OrderedProduct, promotions []Promotion) ([]Discount, error) {
+	left := map[string]float64{}
+	for _, l := range lines {
+		left[l.ID] = roundCents(l.Price * float64(l.Quantity))
+	}
+
+	discounts := []Discount{}
+	take := func(code string, productID string, amount float64) {
+		amount = roundCents(amount)
+		if amount > left[productID] {
+			amount = left[productID]
+		}
+		if amount <= 0 {
+			return
+		}
+		left[productID] = roundCents(left[productID] - amount)
+		discounts = append(discounts, Discount{ProductID: productID, Code: code, Amount: amount})
+	}
+
+	for _, kind := range []PromotionKind{PromotionBuyXGetY, PromotionPercent, PromotionFixed} {
+		for _, p := range promotions {
+			if p.Kind != kind {
+				continue
+			}
+
+			// The lines the promotion applies to.
+			targets := []OrderedProduct{}
+			base := 0.0
+			for _, l := range lines {
+				if (p.ProductID == "" || l.ID == p.ProductID) && left[l.ID] > 0 {
+					targets = append(targets, l)
+					base += left[l.ID]
+				}
+			}
+			before := len(discounts)
+
+			switch p.Kind {
+			case PromotionBuyXGetY:
+				for _, l := range targets {
+					free := l.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+					take(p.Code, l.ID, l.Price*float64(free))
+				}
+			case PromotionPercent:
+				for _, l := range targets {
+					take(p.Code, l.ID, left[l.ID]*p.Value/100)
+				}
+			case PromotionFixed:
+				// Split over the lines by what is left of them, the last
+				// line takes what rounding leaves over.
+				amount := p.Value
+				if amount > base {
+					amount = base
+				}
+				remaining := roundCents(amount)
+				for i, l := range targets {
+					share := roundCents(amount * left[l.ID] / base)
+					if i == len(targets)-1 || share > remaining {
+						share = remaining
+					}
+					remaining = roundCents(remaining - share)
+					take(p.Code, l.ID, share)
+				}
+			}
+
+			if len(discounts) == before {
+				return nil, fmt.Errorf("%w: %s", ErrCouponNotApplicable, p.Code)
+			}
+		}
+	}
+	return discounts, nil
+}
+
+// promotions looks up the coupons of an order and checks that they can be
+// used now, together and by the account. Usage limits are checked again
+// when the order is stored.
+func (s *orderService) promotions(ctx context.Context, accountID string, codes []string, now time.Time) ([]Promotion, error) {
+	promotions := []Promotion{}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		code = NormalizeCouponCode(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		p, err := s.repository.GetPromotion(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if !p.activeAt(now) {
+			return nil, fmt.Errorf("%w: %s", ErrCouponNotValid, code)
+		}
+		uses, accountUses, err := s.repository.CountRedemptions(ctx, code, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if (p.MaxUses > 0 && uses >= p.MaxUses) || (p.MaxUsesPerAccount > 0 && accountUses >= p.MaxUsesPerAccount) {
+			return nil, fmt.Errorf("%w: %s", ErrCouponLimitReached, code)
+		}
+		promotions = append(promotions, *p)
+	}
+
+	if len(promotions) > 1 {
+		for _, p := range promotions {
+			if !p.Stackable {
+				return nil, fmt.Errorf("%w: %s", ErrCouponNotStackable, p.Code)
+			}
+		}
+	}
+	return promotions, nil
+}
+
+func (s *orderService) CreatePromotion(ctx context.Context, p Promotion) (*Promotion, error) {
+	p.Code = NormalizeCouponCode(p.Code)
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	p.CreatedAt = time.Now().UTC()
+	if err := s.repository.PutPromotion(ctx, p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *orderService) GetPromotion(ctx context.Context, code string) (*Promotion, error) {
+	return s.repository.GetPromotion(ctx, NormalizeCouponCode(code))
+}
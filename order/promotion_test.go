@@ -0,0 +1,199 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyPromotions(t *testing.T) {
+	lines := []OrderedProduct{
+		{ID: "a", Price: 10, Quantity: 1},
+		{ID: "b", Price: 20, Quantity: 1},
+	}
+
+	tests := []struct {
+		name       string
+		lines      []OrderedProduct
+		promotions []Promotion
+		want       []Discount
+		err        error
+	}{
+		{
+			name:       "percent off every line",
+			lines:      lines,
+			promotions: []Promotion{{Code: "P10", Kind: PromotionPercent, Value: 10}},
+			want: []Discount{
+				{ProductID: "a", Code: "P10", Amount: 1},
+				{ProductID: "b", Code: "P10", Amount: 2},
+			},
+		},
+		{
+			name:       "percent off one product",
+			lines:      lines,
+			promotions: []Promotion{{Code: "P50", Kind: PromotionPercent, Value: 50, ProductID: "b"}},
+			want:       []Discount{{ProductID: "b", Code: "P50", Amount: 10}},
+		},
+		{
+			name:       "fixed split by line value, last line takes the rounding",
+			lines:      lines,
+			promotions: []Promotion{{Code: "F10", Kind: PromotionFixed, Value: 10}},
+			want: []Discount{
+				{ProductID: "a", Code: "F10", Amount: 3.33},
+				{ProductID: "b", Code: "F10", Amount: 6.67},
+			},
+		},
+		{
+			name:  "buy x get y goes before percent",
+			lines: []OrderedProduct{{ID: "a", Price: 5, Quantity: 7}},
+			promotions: []Promotion{
+				{Code: "P10", Kind: PromotionPercent, Value: 10},
+				{Code: "B2G1", Kind: PromotionBuyXGetY, ProductID: "a", BuyQuantity: 2, GetQuantity: 1},
+			},
+			want: []Discount{
+				{ProductID: "a", Code: "B2G1", Amount: 10},
+				{ProductID: "a", Code: "P10", Amount: 2.5},
+			},
+		},
+		{
+			name:  "percent goes before fixed",
+			lines: []OrderedProduct{{ID: "a", Price: 100, Quantity: 1}},
+			promotions: []Promotion{
+				{Code: "F10", Kind: PromotionFixed, Value: 10},
+				{Code: "P10", Kind: PromotionPercent, Value: 10},
+			},
+			want: []Discount{
+				{ProductID: "a", Code: "P10", Amount: 10},
+				{ProductID: "a", Code: "F10", Amount: 10},
+			},
+		},
+		{
+			name:       "fixed clamps at the order value",
+			lines:      lines,
+			promotions: []Promotion{{Code: "F100", Kind: PromotionFixed, Value: 100}},
+			want: []Discount{
+				{ProductID: "a", Code: "F100", Amount: 10},
+				{ProductID: "b", Code: "F100", Amount: 20},
+			},
+		},
+		{
+			name:  "nothing left after a full discount",
+			lines: lines,
+			promotions: []Promotion{
+				{Code: "P100", Kind: PromotionPercent, Value: 100},
+				{Code: "F5", Kind: PromotionFixed, Value: 5},
+			},
+			err: ErrCouponNotApplicable,
+		},
+		{
+			name:       "product not in the order",
+			lines:      lines,
+			promotions: []Promotion{{Code: "P10", Kind: PromotionPercent, Value: 10, ProductID: "c"}},
+			err:        ErrCouponNotApplicable,
+		},
+		{
+			name:       "too few units for buy x get y",
+			lines:      []OrderedProduct{{ID: "a", Price: 5, Quantity: 2}},
+			promotions: []Promotion{{Code: "B2G1", Kind: PromotionBuyXGetY, ProductID: "a", BuyQuantity: 2, GetQuantity: 1}},
+			err:        ErrCouponNotApplicable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPromotions(tt.lines, tt.promotions)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("discounts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// promotionRepository serves promotions and redemption counts from memory.
+type promotionRepository struct {
+	Repository
+	promotions  map[string]Promotion
+	uses        map[string]uint64
+	accountUses map[string]uint64
+}
+
+func (r *promotionRepository) GetPromotion(ctx context.Context, code string) (*Promotion, error) {
+	p, ok := r.promotions[code]
+	if !ok {
+		return nil, ErrCouponNotFound
+	}
+	return &p, nil
+}
+
+func (r *promotionRepository) CountRedemptions(ctx context.Context, code string, accountID string) (uint64, uint64, error) {
+	return r.uses[code], r.accountUses[code], nil
+}
+
+func TestPromotions(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	r := &promotionRepository{
+		promotions: map[string]Promotion{
+			"SAVE10":   {Code: "SAVE10", Kind: PromotionPercent, Value: 10},
+			"OTHER":    {Code: "OTHER", Kind: PromotionFixed, Value: 5},
+			"STACK1":   {Code: "STACK1", Kind: PromotionPercent, Value: 5, Stackable: true},
+			"STACK2":   {Code: "STACK2", Kind: PromotionFixed, Value: 1, Stackable: true},
+			"LATER":    {Code: "LATER", Kind: PromotionPercent, Value: 10, StartsAt: &after},
+			"OVER":     {Code: "OVER", Kind: PromotionPercent, Value: 10, EndsAt: &before},
+			"USEDUP":   {Code: "USEDUP", Kind: PromotionPercent, Value: 10, MaxUses: 3},
+			"ONCE":     {Code: "ONCE", Kind: PromotionPercent, Value: 10, MaxUsesPerAccount: 1},
+			"PLENTY":   {Code: "PLENTY", Kind: PromotionPercent, Value: 10, MaxUses: 4, MaxUsesPerAccount: 2},
+			"WINDOWED": {Code: "WINDOWED", Kind: PromotionPercent, Value: 10, StartsAt: &before, EndsAt: &after},
+		},
+		uses:        map[string]uint64{"USEDUP": 3, "PLENTY": 3},
+		accountUses: map[string]uint64{"ONCE": 1, "PLENTY": 1},
+	}
+	s := &orderService{repository: r}
+
+	tests := []struct {
+		name  string
+		codes []string
+		want  []string
+		err   error
+	}{
+		{name: "no coupons", codes: nil, want: []string{}},
+		{name: "normalized and deduplicated", codes: []string{" save10 ", "SAVE10", ""}, want: []string{"SAVE10"}},
+		{name: "unknown code", codes: []string{"NOPE"}, err: ErrCouponNotFound},
+		{name: "not started", codes: []string{"LATER"}, err: ErrCouponNotValid},
+		{name: "ended", codes: []string{"OVER"}, err: ErrCouponNotValid},
+		{name: "inside its window", codes: []string{"WINDOWED"}, want: []string{"WINDOWED"}},
+		{name: "total uses reached", codes: []string{"USEDUP"}, err: ErrCouponLimitReached},
+		{name: "account uses reached", codes: []string{"ONCE"}, err: ErrCouponLimitReached},
+		{name: "under both limits", codes: []string{"PLENTY"}, want: []string{"PLENTY"}},
+		{name: "stackable together", codes: []string{"STACK1", "STACK2"}, want: []string{"STACK1", "STACK2"}},
+		{name: "non-stackable with stackable", codes: []string{"STACK1", "SAVE10"}, err: ErrCouponNotStackable},
+		{name: "two non-stackable", codes: []string{"SAVE10", "OTHER"}, err: ErrCouponNotStackable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.promotions(context.Background(), "account", tt.codes, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			codes := []string{}
+			for _, p := range got {
+				codes = append(codes, p.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("codes = %v, want %v", codes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+var (
+	ErrInvalidRefund   = errors.New("Invalid refund")
+	ErrNothingToRefund = errors.New("Nothing left to refund")
+)
+
+// Refund returns money for some or all lines of an order.
+type Refund struct {
+	ID        string       `json:"id"`
+	OrderID   string       `json:"order_id"`
+	Amount    float64      `json:"amount"`
+	Reason    string       `json:"reason"`
+	Actor     string       `json:"actor"`
+	Lines     []RefundLine `json:"lines"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RefundLine struct {
+	ProductID string  `json:"product_id"`
+	Quantity  uint64  `json:"quantity"`
+	Amount    float64 `json:"amount"`
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// RefundedTotal is what the refunds of the order returned so far.
+func (o Order) RefundedTotal() float64 {
+	total := 0.0
+	for _, r := range o.Refunds {
+		total += r.Amount
+	}
+	return roundCents(total)
+}
+
+// NetTotal is what the customer paid minus what was refunded.
+func (o Order) NetTotal() float64 {
+	return roundCents(o.TotalPrice - o.RefundedTotal())
+}
+
+// refundable returns the quantity of each line not refunded yet.
+func (o Order) refundable() map[string]uint64 {
+	remaining := map[string]uint64{}
+	for _, p := range o.Products {
+		remaining[p.ID] += p.Quantity
+	}
+	for _, r := range o.Refunds {
+		for _, l := range r.Lines {
+			remaining[l.ProductID] -= l.Quantity
+		}
+	}
+	return remaining
+}
+
+// newRefund prices the requested lines at what they were ordered for after
+// discounts and with tax, or refunds everything left when lines is empty or
+// takes all of it. The amount never exceeds the net total.
+func (o Order) newRefund(lines []RefundLine, actor string, reason string) (*Refund, error) {
+	remaining := o.refundable()
+	prices := map[string]float64{}
+	for _, p := range o.Products {
+		prices[p.ID] = p.Price
+		if p.Quantity > 0 {
+			prices[p.ID] -= o.lineDiscount(p.ID) / float64(p.Quantity)
+			if o.TaxMode == TaxExclusive {
+				prices[p.ID] += o.lineTax(p.ID) / float64(p.Quantity)
+			}
+		}
+	}
+
+	open := 0
+	for _, quantity := range remaining {
+		if quantity > 0 {
+			open++
+		}
+	}
+
+	whole := len(lines) == 0
+	if whole {
+		for _, p := range o.Products {
+			if remaining[p.ID] > 0 {
+				lines = append(lines, RefundLine{ProductID: p.ID, Quantity: remaining[p.ID]})
+			}
+		}
+	}
+
+	refund := &Refund{
+		ID:        ksuid.New().String(),
+		OrderID:   o.ID,
+		Reason:    reason,
+		Actor:     actor,
+		Lines:     []RefundLine{},
+		CreatedAt: time.Now().UTC(),
+	}
+	seen := map[string]bool{}
+	covered := 0
+	for _, l := range lines {
+		left, ok := remaining[l.ProductID]
+		if !ok || seen[l.ProductID] || l.Quantity == 0 || l.Quantity > left {
+			return nil, fmt.Errorf("%w line %s", ErrInvalidRefund, l.ProductID)
+		}
+		seen[l.ProductID] = true
+		if l.Quantity == left {
+			covered++
+		}
+
+		l.Amount = roundCents(prices[l.ProductID] * float64(l.Quantity))
+		refund.Lines = append(refund.Lines, l)
+		refund.Amount += l.Amount
+	}
+
+	// Lines of orders placed before lines kept their price are worth
+	// nothing on their own and per line rounding can be a cent off,
+	// refunding everything takes what is left of the total instead.
+	whole = whole || covered == open
+	if whole || refund.Amount > o.NetTotal() {
+		refund.Amount = o.NetTotal()
+	}
+	refund.Amount = roundCents(refund.Amount)
+	if refund.Amount <= 0 && len(refund.Lines) == 0 {
+		return nil, ErrNothingToRefund
+	}
+	return refund, nil
+}
+
+// releaseStock puts the products of lines back into stock. A failure is
+// only logged, the order change it follows has already happened.
+func (s *orderService) releaseStock(ctx context.Context, o *Order, lines map[string]uint64) {
+	if s.inventory == nil {
+		return
+	}
+
+	released := Order{ID: o.ID}
+	for _, p := range o.Products {
+		quantity := lines[p.ID]
+		if quantity == 0 {
+			continue
+		}
+		line := OrderedProduct{ID: p.ID, Quantity: quantity}
+		for _, c := range p.Components {
+			line.Components = append(line.Components, OrderedComponent{
+				ProductID: c.ProductID,
+				Quantity:  c.Quantity / p.Quantity * quantity,
+			})
+		}
+		released.Products = append(released.Products, line)
+	}
+
+	if err := s.inventory.Release(ctx, o.ID, released.StockLines()); err != nil {
+		log.Println("Error releasing stock: ", err)
+	}
+}
+
+// voidPayment voids the payment authorized when the order was placed. A
+// failure is only logged, see releaseStock. Orders placed before sagas
+// have no authorization.
+func (s *orderService) voidPayment(ctx context.Context, id string) {
+	saga, err := s.repository.GetSaga(ctx, id)
+	if err == ErrSagaNotFound {
+		return
+	}
+	if err == nil && saga.AuthorizationID != "" {
+		err = s.payments.Void(ctx, saga.AuthorizationID)
+	}
+	if err != nil {
+		log.Println("Error voiding payment: ", err)
+	}
+}
+
+// CancelOrder cancels an order on behalf of actor. When accountID is set
+// the order must belong to it. Whatever was paid is refunded, a payment
+// that was only authorized is voided.
+func (s *orderService) CancelOrder(ctx context.Context, id string, accountID string, actor string, reason string) (*Order, error) {
+	o, err := s.repository.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if accountID != "" && o.AccountID != accountID {
+		return nil, ErrOrderNotFound
+	}
+	if !o.Status.CanMoveTo(StatusCancelled) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrIllegalTransition, o.Status, StatusCancelled)
+	}
+
+	change := StatusChange{From: o.Status, To: StatusCancelled, Actor: actor, CreatedAt: time.Now().UTC()}
+	remaining := o.refundable()
+	if o.Status == StatusPending {
+		err = s.repository.UpdateOrderStatus(ctx, id, change)
+	} else {
+		var refund *Refund
+		refund, err = o.newRefund(nil, actor, reason)
+		if err == ErrNothingToRefund {
+			err = s.repository.UpdateOrderStatus(ctx, id, change)
+		} else if err == nil {
+			err = s.repository.PutRefund(ctx, *refund, &change)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Status == StatusPending {
+		s.voidPayment(ctx, id)
+	}
+	s.releaseStock(ctx, o, remaining)
+	return s.repository.GetOrder(ctx, id)
+}
+
+// RefundOrder refunds the given lines of a paid order, or all that is left
+// when lines is empty. Refunding everything moves the order to refunded.
+// Stock comes back only for orders that have not shipped.
+func (s *orderService) RefundOrder(ctx context.Context, id string, lines []RefundLine, actor string, reason string) (*Order, error) {
+	o, err := s.repository.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !o.Status.CanMoveTo(StatusRefunded) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrIllegalTransition, o.Status, StatusRefunded)
+	}
+
+	refund, err := o.newRefund(lines, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	refunded := map[string]uint64{}
+	for _, l := range refund.Lines {
+		refunded[l.ProductID] = l.Quantity
+	}
+	var change *StatusChange
+	left := false
+	for productID, quantity := range o.refundable() {
+		if quantity > refunded[productID] {
+			left = true
+		}
+	}
+	if !left {
+		change = &StatusChange{From: o.Status, To: StatusRefunded, Actor: actor, CreatedAt: refund.CreatedAt}
+	}
+
+	if err := s.repository.PutRefund(ctx, *refund, change); err != nil {
+		return nil, err
+	}
+
+	if o.Status == StatusPaid || o.Status == StatusFulfilled {
+		s.releaseStock(ctx, o, refunded)
+	}
+	return s.repository.GetOrder(ctx, id)
+}
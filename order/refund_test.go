@@ -0,0 +1,229 @@
+package order
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// refundableOrder is a paid order of 3 a at 10 with 3 off and 2 b at 5,
+// taxed at 10%.
+func refundableOrder(mode TaxMode) Order {
+	o := Order{
+		ID: "order",
+		Products: []OrderedProduct{
+			{ID: "a", Price: 10, Quantity: 3},
+			{ID: "b", Price: 5, Quantity: 2},
+		},
+		Status:     StatusPaid,
+		Discounts:  []Discount{{ProductID: "a", Code: "F3", Amount: 3}},
+		TaxMode:    mode,
+		TotalPrice: 37,
+	}
+	if mode == TaxExclusive {
+		o.TaxLines = []TaxLine{
+			{ProductID: "a", Rate: 0.1, Taxable: 27, Amount: 2.7},
+			{ProductID: "b", Rate: 0.1, Taxable: 10, Amount: 1},
+		}
+		o.TotalPrice = 40.7
+	} else {
+		o.TaxLines = []TaxLine{
+			{ProductID: "a", Rate: 0.1, Taxable: 27, Amount: 2.45},
+			{ProductID: "b", Rate: 0.1, Taxable: 10, Amount: 0.91},
+		}
+	}
+	return o
+}
+
+// roundingOrder is a paid order of 3 a at 1 with 2 off, a unit is worth
+// 0.33 and a third of a cent.
+func roundingOrder() Order {
+	return Order{
+		ID:         "order",
+		Products:   []OrderedProduct{{ID: "a", Price: 1, Quantity: 3}},
+		Status:     StatusPaid,
+		Discounts:  []Discount{{ProductID: "a", Code: "F2", Amount: 2}},
+		TaxMode:    TaxExclusive,
+		TotalPrice: 1,
+	}
+}
+
+func TestNewRefund(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   Order
+		refunds []Refund
+		lines   []RefundLine
+		want    []RefundLine
+		amount  float64
+		err     error
+	}{
+		{
+			name:   "line after discount with exclusive tax",
+			order:  refundableOrder(TaxExclusive),
+			lines:  []RefundLine{{ProductID: "a", Quantity: 1}},
+			want:   []RefundLine{{ProductID: "a", Quantity: 1, Amount: 9.9}},
+			amount: 9.9,
+		},
+		{
+			name:   "line after discount with inclusive tax",
+			order:  refundableOrder(TaxInclusive),
+			lines:  []RefundLine{{ProductID: "a", Quantity: 1}},
+			want:   []RefundLine{{ProductID: "a", Quantity: 1, Amount: 9}},
+			amount: 9,
+		},
+		{
+			name:  "several lines",
+			order: refundableOrder(TaxExclusive),
+			lines: []RefundLine{{ProductID: "a", Quantity: 2}, {ProductID: "b", Quantity: 1}},
+			want: []RefundLine{
+				{ProductID: "a", Quantity: 2, Amount: 19.8},
+				{ProductID: "b", Quantity: 1, Amount: 5.5},
+			},
+			amount: 25.3,
+		},
+		{
+			name:  "everything",
+			order: refundableOrder(TaxExclusive),
+			want: []RefundLine{
+				{ProductID: "a", Quantity: 3, Amount: 29.7},
+				{ProductID: "b", Quantity: 2, Amount: 11},
+			},
+			amount: 40.7,
+		},
+		{
+			name:    "rest listed line by line",
+			order:   refundableOrder(TaxExclusive),
+			refunds: []Refund{{Amount: 9.9, Lines: []RefundLine{{ProductID: "a", Quantity: 1, Amount: 9.9}}}},
+			lines:   []RefundLine{{ProductID: "b", Quantity: 2}, {ProductID: "a", Quantity: 2}},
+			want: []RefundLine{
+				{ProductID: "b", Quantity: 2, Amount: 11},
+				{ProductID: "a", Quantity: 2, Amount: 19.8},
+			},
+			amount: 30.8,
+		},
+		{
+			name: "every line of an order without line prices",
+			order: Order{
+				ID:         "order",
+				Products:   []OrderedProduct{{ID: "a", Quantity: 3}, {ID: "b", Quantity: 2}},
+				Status:     StatusPaid,
+				TaxMode:    TaxExclusive,
+				TotalPrice: 40,
+			},
+			lines: []RefundLine{{ProductID: "a", Quantity: 3}, {ProductID: "b", Quantity: 2}},
+			want: []RefundLine{
+				{ProductID: "a", Quantity: 3},
+				{ProductID: "b", Quantity: 2},
+			},
+			amount: 40,
+		},
+		{
+			name:  "last line takes the rounding",
+			order: roundingOrder(),
+			refunds: []Refund{
+				{Amount: 0.33, Lines: []RefundLine{{ProductID: "a", Quantity: 1, Amount: 0.33}}},
+				{Amount: 0.33, Lines: []RefundLine{{ProductID: "a", Quantity: 1, Amount: 0.33}}},
+			},
+			lines:  []RefundLine{{ProductID: "a", Quantity: 1}},
+			want:   []RefundLine{{ProductID: "a", Quantity: 1, Amount: 0.33}},
+			amount: 0.34,
+		},
+		{
+			name:    "clamped to the net total",
+			order:   refundableOrder(TaxExclusive),
+			refunds: []Refund{{Amount: 35, Lines: []RefundLine{{ProductID: "b", Quantity: 2, Amount: 35}}}},
+			lines:   []RefundLine{{ProductID: "a", Quantity: 3}},
+			want:    []RefundLine{{ProductID: "a", Quantity: 3, Amount: 29.7}},
+			amount:  5.7,
+		},
+		{
+			name:  "unknown product",
+			order: refundableOrder(TaxExclusive),
+			lines: []RefundLine{{ProductID: "c", Quantity: 1}},
+			err:   ErrInvalidRefund,
+		},
+		{
+			name:  "no quantity",
+			order: refundableOrder(TaxExclusive),
+			lines: []RefundLine{{ProductID: "a"}},
+			err:   ErrInvalidRefund,
+		},
+		{
+			name:  "more than ordered",
+			order: refundableOrder(TaxExclusive),
+			lines: []RefundLine{{ProductID: "b", Quantity: 3}},
+			err:   ErrInvalidRefund,
+		},
+		{
+			name:  "same product twice",
+			order: refundableOrder(TaxExclusive),
+			lines: []RefundLine{{ProductID: "a", Quantity: 1}, {ProductID: "a", Quantity: 1}},
+			err:   ErrInvalidRefund,
+		},
+		{
+			name:    "more than is left",
+			order:   refundableOrder(TaxExclusive),
+			refunds: []Refund{{Amount: 9.9, Lines: []RefundLine{{ProductID: "a", Quantity: 1, Amount: 9.9}}}},
+			lines:   []RefundLine{{ProductID: "a", Quantity: 3}},
+			err:     ErrInvalidRefund,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.order.Refunds = tt.refunds
+			refund, err := tt.order.newRefund(tt.lines, "admin", "test")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if !reflect.DeepEqual(refund.Lines, tt.want) {
+				t.Errorf("lines = %+v, want %+v", refund.Lines, tt.want)
+			}
+			if refund.Amount != tt.amount {
+				t.Errorf("amount = %v, want %v", refund.Amount, tt.amount)
+			}
+		})
+	}
+}
+
+func TestNewRefundRepeated(t *testing.T) {
+	o := refundableOrder(TaxExclusive)
+	steps := []struct {
+		lines  []RefundLine
+		amount float64
+		net    float64
+	}{
+		{lines: []RefundLine{{ProductID: "a", Quantity: 1}}, amount: 9.9, net: 30.8},
+		{lines: []RefundLine{{ProductID: "b", Quantity: 1}}, amount: 5.5, net: 25.3},
+		{lines: []RefundLine{{ProductID: "a", Quantity: 1}}, amount: 9.9, net: 15.4},
+		{lines: nil, amount: 15.4, net: 0},
+	}
+
+	for i, step := range steps {
+		refund, err := o.newRefund(step.lines, "admin", "test")
+		if err != nil {
+			t.Fatalf("refund %d: %v", i, err)
+		}
+		if refund.Amount != step.amount {
+			t.Errorf("refund %d amount = %v, want %v", i, refund.Amount, step.amount)
+		}
+		o.Refunds = append(o.Refunds, *refund)
+		if o.NetTotal() != step.net {
+			t.Errorf("net total after refund %d = %v, want %v", i, o.NetTotal(), step.net)
+		}
+	}
+
+	if left := o.refundable(); left["a"] != 0 || left["b"] != 0 {
+		t.Errorf("refundable = %v, want nothing", left)
+	}
+	if _, err := o.newRefund(nil, "admin", "test"); !errors.Is(err, ErrNothingToRefund) {
+		t.Errorf("err = %v, want %v", err, ErrNothingToRefund)
+	}
+	if _, err := o.newRefund([]RefundLine{{ProductID: "a", Quantity: 1}}, "admin", "test"); !errors.Is(err, ErrInvalidRefund) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidRefund)
+	}
+}
@@ -3,6 +3,10 @@ package order
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -12,7 +16,24 @@ type Repository interface {
 	Close()
 	PutOrder(ctx context.Context, o Order) error
 	GetOrderForAccount(ctx context.Context, accountID string) ([]*Order, error)
-	ListOrders(ctx context.Context, skip uint64, take uint64) ([]Order, error)
+	ListOrders(ctx context.Context, filter OrderFilter, skip uint64, take uint64) ([]Order, error)
+	GetCoPurchasedProducts(ctx context.Context, productID string, limit uint64) ([]CoPurchase, error)
+	ListOrderedProducts(ctx context.Context, after string, take uint64) ([]ProductReference, error)
+	GetOrder(ctx context.Context, id string) (*Order, error)
+	UpdateOrderStatus(ctx context.Context, id string, change StatusChange) error
+	PutRefund(ctx context.Context, refund Refund, change *StatusChange) error
+	PutSaga(ctx context.Context, saga OrderSaga) error
+	GetSaga(ctx context.Context, id string) (*OrderSaga, error)
+	TouchSaga(ctx context.Context, id string) error
+	ClaimStaleSagas(ctx context.Context, before time.Time) ([]OrderSaga, error)
+	RelayEvents(ctx context.Context, take uint64, publish func(ctx context.Context, e Event) error) (uint64, error)
+	GetCart(ctx context.Context, owner CartOwner) (*Cart, error)
+	PutCart(ctx context.Context, c Cart) error
+	DeleteCart(ctx context.Context, id string) error
+	DeleteExpiredCarts(ctx context.Context, before time.Time) (uint64, error)
+	PutPromotion(ctx context.Context, p Promotion) error
+	GetPromotion(ctx context.Context, code string) (*Promotion, error)
+	CountRedemptions(ctx context.Context, code string, accountID string) (uint64, uint64, error)
 }
 
 type postgresRepository struct {
@@ -50,16 +71,25 @@ func (r *postgresRepository) PutOrder(ctx context.Context, o Order) (err error)
 		}
 	}()
 
-	_, err = tx.ExecContext(ctx, "INSERT INTO orders(id, account_id, created_at, total_price) VALUES($1, $2, $3, $4)", o.ID, o.AccountID, o.CreatedAt, o.TotalPrice)
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO orders(id, account_id, created_at, price, status, country, region, tax_mode) VALUES($1, $2, $3, $4, $5, $6, $7, $8)",
+		o.ID, o.AccountID, o.CreatedAt, o.TotalPrice, o.Status, o.Address.Country, o.Address.Region, o.TaxMode,
+	)
 	if err != nil {
 		return err
 	}
 
-	stmt, _ := tx.PrepareContext(ctx, pq.CopyIn("order_products", "order_id", "product_id", "quantity"))
+	for _, change := range o.History {
+		if err = putStatusChange(ctx, tx, o.ID, change); err != nil {
+			return err
+		}
+	}
+
+	stmt, _ := tx.PrepareContext(ctx, pq.CopyIn("order_products", "order_id", "product_id", "quantity", "price", "name", "description", "currency"))
 	defer stmt.Close()
 
 	for _, p := range o.Products {
-		_, err = stmt.ExecContext(ctx, o.ID, p.ID, p.Quantity)
+		_, err = stmt.ExecContext(ctx, o.ID, p.ID, p.Quantity, p.Price, p.Name, p.Description, p.Currency)
 		if err != nil {
 			return err
 		}
@@ -70,127 +100,939 @@ func (r *postgresRepository) PutOrder(ctx context.Context, o Order) (err error)
 		return err
 	}
 
+	if err = putComponents(ctx, tx, o); err != nil {
+		return err
+	}
+
+	if err = putDiscounts(ctx, tx, o); err != nil {
+		return err
+	}
+
+	for _, t := range o.TaxLines {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO order_tax_lines(order_id, product_id, tax_class, jurisdiction, rate, taxable, amount) VALUES($1, $2, $3, $4, $5, $6, $7)",
+			o.ID, t.ProductID, t.TaxClass, t.Jurisdiction, t.Rate, t.Taxable, t.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return putEvent(ctx, tx, o.ID, EventOrderPlaced, o, o.CreatedAt)
+
+}
+
+func putComponents(ctx context.Context, tx *sql.Tx, o Order) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("order_product_components", "order_id", "bundle_id", "product_id", "quantity"))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range o.Products {
+		for _, c := range p.Components {
+			if _, err = stmt.ExecContext(ctx, o.ID, p.ID, c.ProductID, c.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// putDiscounts stores the discounts of an order and redeems its coupons.
+// Coupons are locked while their usage is counted, so concurrent orders
+// cannot use more than the limits allow.
+func putDiscounts(ctx context.Context, tx *sql.Tx, o Order) error {
+	codes := []string{}
+	seen := map[string]bool{}
+	for _, d := range o.Discounts {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO order_discounts(order_id, product_id, code, amount) VALUES($1, $2, $3, $4)",
+			o.ID, d.ProductID, d.Code, d.Amount,
+		)
+		if err != nil {
+			return err
+		}
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			codes = append(codes, d.Code)
+		}
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		var maxUses, maxUsesPerAccount uint64
+		err := tx.QueryRowContext(ctx,
+			"SELECT max_uses, max_uses_per_account FROM promotions WHERE code = $1 FOR UPDATE",
+			code,
+		).Scan(&maxUses, &maxUsesPerAccount)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrCouponNotFound, code)
+		}
+		if err != nil {
+			return err
+		}
+
+		var uses, accountUses uint64
+		err = tx.QueryRowContext(ctx,
+			"SELECT COUNT(*), COUNT(*) FILTER (WHERE account_id = $2) FROM promotion_redemptions WHERE code = $1",
+			code, o.AccountID,
+		).Scan(&uses, &accountUses)
+		if err != nil {
+			return err
+		}
+		if (maxUses > 0 && uses >= maxUses) || (maxUsesPerAccount > 0 && accountUses >= maxUsesPerAccount) {
+			return fmt.Errorf("%w: %s", ErrCouponLimitReached, code)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO promotion_redemptions(code, order_id, account_id, created_at) VALUES($1, $2, $3, $4)",
+			code, o.ID, o.AccountID, o.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
+}
+
+// GetOrder returns an order with its lines, status history and refunds.
+func (r *postgresRepository) GetOrder(ctx context.Context, id string) (*Order, error) {
+	o := &Order{ID: id, Products: []OrderedProduct{}, History: []StatusChange{}, Refunds: []Refund{}, Discounts: []Discount{}, TaxLines: []TaxLine{}}
+	err := r.db.QueryRowContext(ctx,
+		"SELECT account_id, created_at, price::numeric::float8, status, country, region, tax_mode FROM orders WHERE id = $1",
+		id,
+	).Scan(&o.AccountID, &o.CreatedAt, &o.TotalPrice, &o.Status, &o.Address.Country, &o.Address.Region, &o.TaxMode)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	if err = r.loadDetails(ctx, map[string]*Order{id: o}); err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
-func (r *postgresRepository) GetOrderForAccount(ctx context.Context, accountID string) ([]*Order, error) {
+// UpdateOrderStatus applies a transition only if the order still has the
+// status it moves from, so concurrent transitions cannot both succeed.
+func (r *postgresRepository) UpdateOrderStatus(ctx context.Context, id string, change StatusChange) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, "UPDATE orders SET status = $1 WHERE id = $2 AND status = $3", change.To, id, change.From)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStatusConflict
+	}
+
+	return putStatusChange(ctx, tx, id, change)
+}
+
+// putStatusChange records a change in the history of an order. Changes
+// after the order was placed also go to the outbox, cancellations as
+// OrderCancelled.
+func putStatusChange(ctx context.Context, tx *sql.Tx, orderID string, change StatusChange) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO order_status_history(order_id, from_status, to_status, actor, created_at) VALUES($1, $2, $3, $4, $5)",
+		orderID, change.From, change.To, change.Actor, change.CreatedAt,
+	)
+	if err != nil || change.From == "" {
+		return err
+	}
+
+	// Coupons of cancelled orders can be used again.
+	if change.To == StatusCancelled {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM promotion_redemptions WHERE order_id = $1", orderID); err != nil {
+			return err
+		}
+	}
+
+	eventType := EventOrderStatusChanged
+	if change.To == StatusCancelled {
+		eventType = EventOrderCancelled
+	}
+	return putEvent(ctx, tx, orderID, eventType, StatusChangeEvent{OrderID: orderID, StatusChange: change}, change.CreatedAt)
+}
+
+func putEvent(ctx context.Context, tx *sql.Tx, orderID string, eventType EventType, payload interface{}, createdAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO order_outbox(order_id, type, payload, created_at, next_attempt_at) VALUES($1, $2, $3, $4, $4)",
+		orderID, eventType, string(data), createdAt,
+	)
+	return err
+}
+
+// PutRefund stores a refund together with the status change it causes, if
+// any. The change only applies if the order still has the status it moves
+// from.
+func (r *postgresRepository) PutRefund(ctx context.Context, refund Refund, change *StatusChange) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	if change != nil {
+		res, err := tx.ExecContext(ctx, "UPDATE orders SET status = $1 WHERE id = $2 AND status = $3", change.To, refund.OrderID, change.From)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrStatusConflict
+		}
+		if err = putStatusChange(ctx, tx, refund.OrderID, *change); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO order_refunds(id, order_id, amount, reason, actor, created_at) VALUES($1, $2, $3, $4, $5, $6)",
+		refund.ID, refund.OrderID, refund.Amount, refund.Reason, refund.Actor, refund.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range refund.Lines {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO order_refund_lines(refund_id, product_id, quantity, amount) VALUES($1, $2, $3, $4)",
+			refund.ID, l.ProductID, l.Quantity, l.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDetails fills in the lines, status history and refunds of orders.
+func (r *postgresRepository) loadDetails(ctx context.Context, orders map[string]*Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	for _, load := range []func(context.Context, map[string]*Order) error{r.loadLines, r.loadComponents, r.loadHistory, r.loadRefunds, r.loadDiscounts, r.loadTaxLines} {
+		if err := load(ctx, orders); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLines fills in the lines of orders, ordered by product id.
+func (r *postgresRepository) loadLines(ctx context.Context, orders map[string]*Order) error {
+	ids := []string{}
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT 
-		o.id, 
-		o.account_id, 
-		o.created_at, 
-		o.total_price::money::numeric::float8
-		op.product_id, 
-		op.quantity
-		FROM orders o
-		INNER JOIN order_products op ON o.id = op.order_id
-		WHERE o.account_id = $1
-		ORDER BY o.id DESC
+		`SELECT
+		order_id,
+		product_id,
+		quantity,
+		price::numeric::float8,
+		name,
+		description,
+		currency
+		FROM order_products
+		WHERE order_id = ANY($1)
+		ORDER BY product_id
 		`,
-		accountID,
+		pq.Array(ids),
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	orderMap := make(map[string]*Order)
+	for rows.Next() {
+		var orderID string
+		p := OrderedProduct{}
+		if err = rows.Scan(&orderID, &p.ID, &p.Quantity, &p.Price, &p.Name, &p.Description, &p.Currency); err != nil {
+			return err
+		}
+		o := orders[orderID]
+		o.Products = append(o.Products, p)
+	}
+	return rows.Err()
+}
+
+// loadRefunds fills in the refunds of orders, oldest first.
+func (r *postgresRepository) loadRefunds(ctx context.Context, orders map[string]*Order) error {
+	ids := []string{}
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT
+		r.id,
+		r.order_id,
+		r.amount::numeric::float8,
+		r.reason,
+		r.actor,
+		r.created_at,
+		l.product_id,
+		l.quantity,
+		l.amount::numeric::float8
+		FROM order_refunds r
+		LEFT JOIN order_refund_lines l ON l.refund_id = r.id
+		WHERE r.order_id = ANY($1)
+		ORDER BY r.id, l.product_id
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
+		refund := Refund{Lines: []RefundLine{}}
 		var (
-			id, accountID string
-			createdAt     time.Time
-			totalPrice    float64
-			productID     string
-			quantity      uint64
+			productID sql.NullString
+			quantity  sql.NullInt64
+			amount    sql.NullFloat64
 		)
-		if err = rows.Scan(&id, &accountID, &createdAt, &totalPrice, &productID, &quantity); err != nil {
-			return nil, err
+		err = rows.Scan(&refund.ID, &refund.OrderID, &refund.Amount, &refund.Reason, &refund.Actor, &refund.CreatedAt, &productID, &quantity, &amount)
+		if err != nil {
+			return err
+		}
+
+		o := orders[refund.OrderID]
+		if n := len(o.Refunds); n == 0 || o.Refunds[n-1].ID != refund.ID {
+			o.Refunds = append(o.Refunds, refund)
 		}
+		if productID.Valid {
+			last := &o.Refunds[len(o.Refunds)-1]
+			last.Lines = append(last.Lines, RefundLine{
+				ProductID: productID.String,
+				Quantity:  uint64(quantity.Int64),
+				Amount:    amount.Float64,
+			})
+		}
+	}
+	return rows.Err()
+}
+
+// loadDiscounts appends the discounts of each order, in the order they
+// were applied.
+func (r *postgresRepository) loadDiscounts(ctx context.Context, orders map[string]*Order) error {
+	ids := []string{}
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT order_id, product_id, code, amount::numeric::float8
+		FROM order_discounts
+		WHERE order_id = ANY($1)
+		ORDER BY id
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-		order, exist := orderMap[id]
-		if !exist {
-			order = &Order{
-				ID:         id,
-				AccountID:  accountID,
-				CreatedAt:  createdAt,
-				TotalPrice: totalPrice,
-				Products:   []OrderedProduct{},
+	for rows.Next() {
+		var orderID string
+		d := Discount{}
+		if err = rows.Scan(&orderID, &d.ProductID, &d.Code, &d.Amount); err != nil {
+			return err
+		}
+		o := orders[orderID]
+		o.Discounts = append(o.Discounts, d)
+	}
+	return rows.Err()
+}
+
+func (r *postgresRepository) loadTaxLines(ctx context.Context, orders map[string]*Order) error {
+	ids := []string{}
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT order_id, product_id, tax_class, jurisdiction, rate, taxable::numeric::float8, amount::numeric::float8
+		FROM order_tax_lines
+		WHERE order_id = ANY($1)
+		ORDER BY id
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID string
+		t := TaxLine{}
+		if err = rows.Scan(&orderID, &t.ProductID, &t.TaxClass, &t.Jurisdiction, &t.Rate, &t.Taxable, &t.Amount); err != nil {
+			return err
+		}
+		o := orders[orderID]
+		o.TaxLines = append(o.TaxLines, t)
+	}
+	return rows.Err()
+}
+
+// loadHistory fills in the status history of orders, oldest first.
+func (r *postgresRepository) loadHistory(ctx context.Context, orders map[string]*Order) error {
+	ids := []string{}
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT order_id, from_status, to_status, actor, created_at
+		FROM order_status_history
+		WHERE order_id = ANY($1)
+		ORDER BY id
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID string
+		c := StatusChange{}
+		if err = rows.Scan(&orderID, &c.From, &c.To, &c.Actor, &c.CreatedAt); err != nil {
+			return err
+		}
+		o := orders[orderID]
+		o.History = append(o.History, c)
+	}
+	return rows.Err()
+}
+
+// loadComponents fills in the components of the bundle lines in orders.
+func (r *postgresRepository) loadComponents(ctx context.Context, orders map[string]*Order) error {
+	ids := []string{}
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT order_id, bundle_id, product_id, quantity
+		FROM order_product_components
+		WHERE order_id = ANY($1)
+		ORDER BY product_id
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID, bundleID string
+		c := OrderedComponent{}
+		if err = rows.Scan(&orderID, &bundleID, &c.ProductID, &c.Quantity); err != nil {
+			return err
+		}
+
+		o := orders[orderID]
+		for i := range o.Products {
+			if o.Products[i].ID == bundleID {
+				o.Products[i].Components = append(o.Products[i].Components, c)
+				break
 			}
-			orderMap[id] = order
+		}
+	}
+	return rows.Err()
+}
+
+func (r *postgresRepository) GetOrderForAccount(ctx context.Context, accountID string) ([]*Order, error) {
+	orders, err := r.ListOrders(ctx, OrderFilter{AccountID: accountID}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*Order{}
+	for i := range orders {
+		res = append(res, &orders[i])
+	}
+	return res, nil
+}
+
+// ListOrders returns the orders matching filter newest first, all of them
+// when take is 0.
+func (r *postgresRepository) ListOrders(ctx context.Context, filter OrderFilter, skip uint64, take uint64) ([]Order, error) {
+	where, args := filter.where()
+	query := `SELECT
+		o.id,
+		o.account_id,
+		o.created_at,
+		o.price::numeric::float8,
+		o.status,
+		o.country,
+		o.region,
+		o.tax_mode
+		FROM orders o
+		WHERE ` + where + `
+		ORDER BY o.id DESC
+		OFFSET ` + strconv.FormatUint(skip, 10)
+	if take > 0 {
+		query += " LIMIT " + strconv.FormatUint(take, 10)
+	}
 
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	orderMap := map[string]*Order{}
+	for rows.Next() {
+		o := &Order{Products: []OrderedProduct{}, History: []StatusChange{}, Refunds: []Refund{}, Discounts: []Discount{}, TaxLines: []TaxLine{}}
+		err = rows.Scan(&o.ID, &o.AccountID, &o.CreatedAt, &o.TotalPrice, &o.Status, &o.Address.Country, &o.Address.Region, &o.TaxMode)
+		if err != nil {
+			return nil, err
 		}
-		order.Products = append(order.Products, OrderedProduct{
-			ID:       productID,
-			Quantity: quantity,
-		})
+		ids = append(ids, o.ID)
+		orderMap[o.ID] = o
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
-	var orders []*Order
-	for _, order := range orderMap {
-		orders = append(orders, order)
+
+	if err = r.loadDetails(ctx, orderMap); err != nil {
+		return nil, err
+	}
+
+	orders := []Order{}
+	for _, id := range ids {
+		orders = append(orders, *orderMap[id])
 	}
 	return orders, nil
 }
 
-func (r *postgresRepository) ListOrders(ctx context.Context, skip uint64, take uint64) ([]Order, error) {
+func (r *postgresRepository) GetCoPurchasedProducts(ctx context.Context, productID string, limit uint64) ([]CoPurchase, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT 
-		o.id, 
-		o.account_id, 
-		o.created_at, 
-		o.total_price::money::numeric::float8
-		op.product_id, 
-		op.quantity
-		FROM orders o
-		INNER JOIN order_products op ON o.id = op.order_id
-		ORDER BY o.id DESC
+		`SELECT
+		other.product_id,
+		COUNT(*) AS orders
+		FROM order_products op
+		INNER JOIN order_products other ON other.order_id = op.order_id AND other.product_id <> op.product_id
+		WHERE op.product_id = $1
+		GROUP BY other.product_id
+		ORDER BY orders DESC, other.product_id
+		LIMIT $2
 		`,
+		productID,
+		limit,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	orderMap := make(map[string]*Order)
+	products := []CoPurchase{}
+	for rows.Next() {
+		p := CoPurchase{}
+		if err = rows.Scan(&p.ProductID, &p.Count); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
 
+// ListOrderedProducts pages through every product id that appears in an
+// order, as a line or a bundle component, ordered by id and starting after
+// the given one. Each comes with the snapshot of its latest order line,
+// empty for products only ever ordered inside bundles or before lines kept
+// snapshots.
+func (r *postgresRepository) ListOrderedProducts(ctx context.Context, after string, take uint64) ([]ProductReference, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`WITH refs AS (
+			SELECT
+			product_id,
+			COUNT(DISTINCT order_id) AS orders
+			FROM (
+				SELECT order_id, product_id FROM order_products
+				UNION ALL
+				SELECT order_id, product_id FROM order_product_components
+			) lines
+			WHERE product_id > $1
+			GROUP BY product_id
+			ORDER BY product_id
+			LIMIT $2
+		)
+		SELECT
+		refs.product_id,
+		refs.orders,
+		COALESCE(latest.name, ''),
+		COALESCE(latest.description, ''),
+		COALESCE(latest.price, 0)
+		FROM refs
+		LEFT JOIN LATERAL (
+			SELECT op.name, op.description, op.price::numeric::float8 AS price
+			FROM order_products op
+			WHERE op.product_id = refs.product_id AND op.name <> ''
+			ORDER BY op.order_id DESC
+			LIMIT 1
+		) latest ON true
+		ORDER BY refs.product_id
+		`,
+		after,
+		take,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []ProductReference{}
 	for rows.Next() {
-		var (
-			id, accountID string
-			createdAt     time.Time
-			totalPrice    float64
-			productID     string
-			quantity      uint64
+		p := ProductReference{}
+		if err = rows.Scan(&p.ProductID, &p.Orders, &p.Name, &p.Description, &p.Price); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// PutSaga saves the whole state of a saga, creating it the first time.
+func (r *postgresRepository) PutSaga(ctx context.Context, saga OrderSaga) error {
+	state, err := json.Marshal(saga)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO order_sagas(id, status, state, created_at, updated_at) VALUES($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, state = EXCLUDED.state, updated_at = EXCLUDED.updated_at`,
+		saga.ID, saga.Status, string(state), saga.CreatedAt, saga.UpdatedAt,
+	)
+	return err
+}
+
+func (r *postgresRepository) GetSaga(ctx context.Context, id string) (*OrderSaga, error) {
+	var state []byte
+	err := r.db.QueryRowContext(ctx, "SELECT state FROM order_sagas WHERE id = $1", id).Scan(&state)
+	if err == sql.ErrNoRows {
+		return nil, ErrSagaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saga := &OrderSaga{}
+	if err = json.Unmarshal(state, saga); err != nil {
+		return nil, err
+	}
+	return saga, nil
+}
+
+// TouchSaga marks a saga as making progress without changing its state.
+func (r *postgresRepository) TouchSaga(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE order_sagas SET updated_at = NOW() WHERE id = $1", id)
+	return err
+}
+
+// ClaimStaleSagas returns the unfinished sagas last saved before the given
+// time. Claiming touches them, so another instance does not claim them
+// too until they go stale again.
+func (r *postgresRepository) ClaimStaleSagas(ctx context.Context, before time.Time) ([]OrderSaga, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`UPDATE order_sagas SET updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM order_sagas
+			WHERE status IN ($1, $2) AND updated_at < $3
+			ORDER BY updated_at
+			LIMIT 100
+			FOR UPDATE SKIP LOCKED
 		)
-		if err = rows.Scan(&id, &accountID, &createdAt, &totalPrice, &productID, &quantity); err != nil {
+		RETURNING state`,
+		SagaRunning, SagaCompensating, before,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sagas := []OrderSaga{}
+	for rows.Next() {
+		var state []byte
+		if err = rows.Scan(&state); err != nil {
+			return nil, err
+		}
+		saga := OrderSaga{}
+		if err = json.Unmarshal(state, &saga); err != nil {
 			return nil, err
 		}
+		sagas = append(sagas, saga)
+	}
+	return sagas, rows.Err()
+}
 
-		order, exist := orderMap[id]
-		if !exist {
-			order = &Order{
-				ID:         id,
-				AccountID:  accountID,
-				CreatedAt:  createdAt,
-				TotalPrice: totalPrice,
-				Products:   []OrderedProduct{},
-			}
-			orderMap[id] = order
+// RelayEvents publishes up to take unpublished events, oldest first, and
+// returns how many it went through. Once an event of an order fails the
+// later ones wait for it. Only one relay runs at a time, a call made while
+// another is running does nothing.
+func (r *postgresRepository) RelayEvents(ctx context.Context, take uint64, publish func(ctx context.Context, e Event) error) (n uint64, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	locked := false
+	if err = tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock(hashtext('order_outbox'))").Scan(&locked); err != nil || !locked {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, order_id, type, payload, created_at, attempts FROM order_outbox
+		WHERE published_at IS NULL AND order_id NOT IN (
+			SELECT order_id FROM order_outbox WHERE published_at IS NULL AND next_attempt_at > NOW()
+		)
+		ORDER BY id
+		LIMIT $1`,
+		take,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	events := []Event{}
+	attempts := map[uint64]int{}
+	for rows.Next() {
+		e := Event{}
+		var payload []byte
+		var a int
+		if err = rows.Scan(&e.ID, &e.OrderID, &e.Type, &payload, &e.CreatedAt, &a); err != nil {
+			rows.Close()
+			return 0, err
 		}
-		order.Products = append(order.Products, OrderedProduct{
-			ID:       productID,
-			Quantity: quantity,
-		})
+		e.Payload = payload
+		events = append(events, e)
+		attempts[e.ID] = a
 	}
+	rows.Close()
 	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	failed := map[string]bool{}
+	for _, e := range events {
+		if failed[e.OrderID] {
+			continue
+		}
+
+		if perr := publish(ctx, e); perr != nil {
+			failed[e.OrderID] = true
+			a := attempts[e.ID] + 1
+			_, err = tx.ExecContext(ctx,
+				"UPDATE order_outbox SET attempts = $2, next_attempt_at = $3, last_error = $4 WHERE id = $1",
+				e.ID, a, time.Now().UTC().Add(retryDelay(a)), perr.Error(),
+			)
+		} else {
+			_, err = tx.ExecContext(ctx, "UPDATE order_outbox SET published_at = NOW() WHERE id = $1", e.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return uint64(len(events)), nil
+}
+
+// GetCart returns the cart of an account, or of a guest when no account is
+// given, expired or not.
+func (r *postgresRepository) GetCart(ctx context.Context, owner CartOwner) (*Cart, error) {
+	query := "SELECT id, account_id, token, created_at, updated_at, expires_at FROM carts WHERE account_id = $1"
+	key := owner.AccountID
+	if owner.AccountID == "" {
+		query = "SELECT id, account_id, token, created_at, updated_at, expires_at FROM carts WHERE token = $1"
+		key = owner.Token
+	}
+	if key == "" {
+		return nil, ErrCartNotFound
+	}
+
+	c := &Cart{Lines: []CartLine{}}
+	var accountID, token sql.NullString
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&c.ID, &accountID, &token, &c.CreatedAt, &c.UpdatedAt, &c.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCartNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
-	var orders []Order
-	for _, order := range orderMap {
-		orders = append(orders, *order)
+	c.AccountID, c.Token = accountID.String, token.String
+
+	rows, err := r.db.QueryContext(ctx, "SELECT product_id, quantity FROM cart_lines WHERE cart_id = $1 ORDER BY position", c.ID)
+	if err != nil {
+		return nil, err
 	}
-	return orders, nil
+	defer rows.Close()
+
+	for rows.Next() {
+		l := CartLine{}
+		if err = rows.Scan(&l.ProductID, &l.Quantity); err != nil {
+			return nil, err
+		}
+		c.Lines = append(c.Lines, l)
+	}
+	return c, rows.Err()
+}
+
+// PutCart stores a cart, replacing its lines.
+func (r *postgresRepository) PutCart(ctx context.Context, c Cart) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO carts(id, account_id, token, created_at, updated_at, expires_at) VALUES($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET updated_at = EXCLUDED.updated_at, expires_at = EXCLUDED.expires_at`,
+		c.ID, c.AccountID, c.Token, c.CreatedAt, c.UpdatedAt, c.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM cart_lines WHERE cart_id = $1", c.ID); err != nil {
+		return err
+	}
+	for i, l := range c.Lines {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO cart_lines(cart_id, product_id, quantity, position) VALUES($1, $2, $3, $4)",
+			c.ID, l.ProductID, l.Quantity, i,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) DeleteCart(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM carts WHERE id = $1", id)
+	return err
+}
+
+func (r *postgresRepository) DeleteExpiredCarts(ctx context.Context, before time.Time) (uint64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM carts WHERE expires_at <= $1", before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return uint64(n), err
+}
+
+func (r *postgresRepository) PutPromotion(ctx context.Context, p Promotion) error {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO promotions(code, kind, value, product_id, buy_quantity, get_quantity, stackable, starts_at, ends_at, max_uses, max_uses_per_account, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (code) DO NOTHING`,
+		p.Code, p.Kind, p.Value, p.ProductID, p.BuyQuantity, p.GetQuantity, p.Stackable, p.StartsAt, p.EndsAt, p.MaxUses, p.MaxUsesPerAccount, p.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: code %s is taken", ErrInvalidPromotion, p.Code)
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetPromotion(ctx context.Context, code string) (*Promotion, error) {
+	p := &Promotion{}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT code, kind, value, product_id, buy_quantity, get_quantity, stackable, starts_at, ends_at, max_uses, max_uses_per_account, created_at
+		FROM promotions WHERE code = $1`,
+		code,
+	).Scan(&p.Code, &p.Kind, &p.Value, &p.ProductID, &p.BuyQuantity, &p.GetQuantity, &p.Stackable, &p.StartsAt, &p.EndsAt, &p.MaxUses, &p.MaxUsesPerAccount, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrCouponNotFound, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CountRedemptions returns how many orders used a coupon, and how many of
+// them the account placed.
+func (r *postgresRepository) CountRedemptions(ctx context.Context, code string, accountID string) (uint64, uint64, error) {
+	var uses, accountUses uint64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE account_id = $2) FROM promotion_redemptions WHERE code = $1",
+		code, accountID,
+	).Scan(&uses, &accountUses)
+	return uses, accountUses, err
 }
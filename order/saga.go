@@ -0,0 +1,293 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/timothydzokoto/grpc_graphql_microservice/account"
+)
+
+type SagaStatus string
+
+const (
+	SagaRunning      SagaStatus = "running"
+	SagaCompleted    SagaStatus = "completed"
+	SagaCompensating SagaStatus = "compensating"
+	SagaFailed       SagaStatus = "failed"
+)
+
+type StepStatus string
+
+const (
+	StepPending     StepStatus = "pending"
+	StepDone        StepStatus = "done"
+	StepFailed      StepStatus = "failed"
+	StepCompensated StepStatus = "compensated"
+)
+
+const (
+	StepValidateAccount  = "validate_account"
+	StepReserveStock     = "reserve_stock"
+	StepAuthorizePayment = "authorize_payment"
+	StepPersistOrder     = "persist_order"
+)
+
+const (
+	// sagaStaleAfter is how long a saga may go untouched before recovery
+	// takes it over.
+	sagaStaleAfter = time.Minute
+	// sagaHeartbeat is how often a running saga is touched, however long
+	// its current step takes, so that only sagas whose runner is gone go
+	// stale.
+	sagaHeartbeat = sagaStaleAfter / 4
+)
+
+var ErrSagaNotFound = errors.New("Order saga not found")
+
+// Accounts confirms that the account placing an order exists.
+type Accounts interface {
+	GetAccount(ctx context.Context, id string) (*account.Account, error)
+}
+
+// OrderSaga is the persisted state of placing one order. Its id is the id
+// of the order.
+type OrderSaga struct {
+	ID              string     `json:"id"`
+	Status          SagaStatus `json:"status"`
+	Order           Order      `json:"order"`
+	Steps           []SagaStep `json:"steps"`
+	AuthorizationID string     `json:"authorization_id,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type SagaStep struct {
+	Name      string     `json:"name"`
+	Status    StepStatus `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// sagaStep runs one step of placing an order. compensate undoes a step
+// that completed, nil when there is nothing to undo. Both must be safe to
+// repeat, recovery runs them again when it cannot tell whether they ran.
+type sagaStep struct {
+	name       string
+	run        func(ctx context.Context, saga *OrderSaga) error
+	compensate func(ctx context.Context, saga *OrderSaga) error
+}
+
+func (s *orderService) sagaSteps() []sagaStep {
+	return []sagaStep{
+		{
+			name: StepValidateAccount,
+			run: func(ctx context.Context, saga *OrderSaga) error {
+				_, err := s.accounts.GetAccount(ctx, saga.Order.AccountID)
+				return err
+			},
+		},
+		{
+			name: StepReserveStock,
+			run: func(ctx context.Context, saga *OrderSaga) error {
+				if s.inventory == nil {
+					return nil
+				}
+				return s.inventory.Reserve(ctx, saga.ID, saga.Order.StockLines())
+			},
+			compensate: func(ctx context.Context, saga *OrderSaga) error {
+				if s.inventory == nil {
+					return nil
+				}
+				return s.inventory.Release(ctx, saga.ID, saga.Order.StockLines())
+			},
+		},
+		{
+			name: StepAuthorizePayment,
+			run: func(ctx context.Context, saga *OrderSaga) error {
+				currency := DefaultCurrency
+				if len(saga.Order.Products) > 0 {
+					currency = saga.Order.Products[0].Currency
+				}
+				id, err := s.payments.Authorize(ctx, saga.ID, saga.Order.AccountID, saga.Order.TotalPrice, currency)
+				if err != nil {
+					return err
+				}
+				saga.AuthorizationID = id
+				return nil
+			},
+			compensate: func(ctx context.Context, saga *OrderSaga) error {
+				return s.payments.Void(ctx, saga.AuthorizationID)
+			},
+		},
+		{
+			name: StepPersistOrder,
+			run: func(ctx context.Context, saga *OrderSaga) error {
+				_, err := s.repository.GetOrder(ctx, saga.ID)
+				if err == nil {
+					return nil
+				}
+				if err != ErrOrderNotFound {
+					return err
+				}
+				return s.repository.PutOrder(ctx, saga.Order)
+			},
+		},
+	}
+}
+
+func newOrderSaga(o Order) *OrderSaga {
+	saga := &OrderSaga{
+		ID:        o.ID,
+		Status:    SagaRunning,
+		Order:     o,
+		Steps:     []SagaStep{},
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.CreatedAt,
+	}
+	for _, step := range []string{StepValidateAccount, StepReserveStock, StepAuthorizePayment, StepPersistOrder} {
+		saga.Steps = append(saga.Steps, SagaStep{Name: step, Status: StepPending, UpdatedAt: o.CreatedAt})
+	}
+	return saga
+}
+
+func (s *orderService) saveSaga(ctx context.Context, saga *OrderSaga) error {
+	saga.UpdatedAt = time.Now().UTC()
+	return s.repository.PutSaga(ctx, *saga)
+}
+
+func (saga *OrderSaga) setStep(i int, status StepStatus, err error) {
+	saga.Steps[i].Status = status
+	saga.Steps[i].UpdatedAt = time.Now().UTC()
+	if err != nil {
+		saga.Steps[i].Error = err.Error()
+	}
+}
+
+// runSaga drives a saga forward from wherever it stopped. When a step
+// fails every completed step is compensated in reverse and the step's
+// error is returned. State is saved after every step so that recovery can
+// pick it up after a crash.
+func (s *orderService) runSaga(ctx context.Context, saga *OrderSaga) error {
+	// Once started a saga runs to an end, a caller giving up must not
+	// leave it halfway.
+	ctx = context.WithoutCancel(ctx)
+	defer s.holdSaga(ctx, saga.ID)()
+	steps := s.sagaSteps()
+
+	var failure error
+	if saga.Status == SagaRunning {
+		for i, step := range steps {
+			if saga.Steps[i].Status == StepDone {
+				continue
+			}
+			if err := step.run(ctx, saga); err != nil {
+				saga.setStep(i, StepFailed, err)
+				saga.Status = SagaCompensating
+				saga.Error = fmt.Sprintf("%s: %v", step.name, err)
+				failure = fmt.Errorf("%s: %w", step.name, err)
+				break
+			}
+			saga.setStep(i, StepDone, nil)
+			if err := s.saveSaga(ctx, saga); err != nil {
+				return err
+			}
+		}
+		if saga.Status == SagaRunning {
+			saga.Status = SagaCompleted
+			return s.saveSaga(ctx, saga)
+		}
+		if err := s.saveSaga(ctx, saga); err != nil {
+			return err
+		}
+	}
+
+	if saga.Status == SagaCompensating {
+		for i := len(steps) - 1; i >= 0; i-- {
+			if saga.Steps[i].Status != StepDone {
+				continue
+			}
+			if steps[i].compensate != nil {
+				if err := steps[i].compensate(ctx, saga); err != nil {
+					return fmt.Errorf("compensating %s: %w", steps[i].name, err)
+				}
+			}
+			saga.setStep(i, StepCompensated, nil)
+			if err := s.saveSaga(ctx, saga); err != nil {
+				return err
+			}
+		}
+		saga.Status = SagaFailed
+		if err := s.saveSaga(ctx, saga); err != nil {
+			return err
+		}
+	}
+
+	if saga.Status == SagaFailed {
+		if failure != nil {
+			return failure
+		}
+		return errors.New(saga.Error)
+	}
+	return nil
+}
+
+// holdSaga touches a saga every sagaHeartbeat until the returned function
+// is called, keeping recovery from claiming it while it runs.
+func (s *orderService) holdSaga(ctx context.Context, id string) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(sagaHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repository.TouchSaga(ctx, id); err != nil {
+					log.Println("Error touching order saga: ", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (s *orderService) GetOrderSaga(ctx context.Context, id string) (*OrderSaga, error) {
+	return s.repository.GetSaga(ctx, id)
+}
+
+// RunSagaRecovery finishes the sagas that stopped making progress, placing
+// their order or compensating it, every interval until ctx is done.
+func (s *orderService) RunSagaRecovery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sagas, err := s.repository.ClaimStaleSagas(ctx, time.Now().UTC().Add(-sagaStaleAfter))
+		if err != nil {
+			log.Println("Error claiming stale order sagas: ", err)
+		}
+		for i := range sagas {
+			if err := s.runSaga(ctx, &sagas[i]); err != nil {
+				log.Printf("Order saga %s: %v", sagas[i].ID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
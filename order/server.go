@@ -8,8 +8,8 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"time"
 
-	"github.com/timothydzokoto/grpc_graphql_microservice/account"
 	"github.com/timothydzokoto/grpc_graphql_microservice/catalog"
 	"github.com/timothydzokoto/grpc_graphql_microservice/order/pb"
 	"google.golang.org/grpc"
@@ -19,25 +19,17 @@ import (
 type grpcServer struct {
 	pb.UnimplementedOrderServiceServer
 	service       Service
-	accountClient *account.Client
 	catalogClient *catalog.Client
 }
 
-func ListenGRPC(s Service, accountURL, catalogURL string, port int) error {
-	accountClient, err := account.NewClient(accountURL)
-	if err != nil {
-		return err
-	}
-
+func ListenGRPC(s Service, catalogURL string, port int) error {
 	catalogClient, err := catalog.NewClient(catalogURL)
 	if err != nil {
-		accountClient.Close()
 		return err
 	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
-		accountClient.Close()
 		catalogClient.Close()
 		return err
 	}
@@ -46,88 +38,186 @@ func ListenGRPC(s Service, accountURL, catalogURL string, port int) error {
 	pb.RegisterOrderServiceServer(serv, &grpcServer{
 		UnimplementedOrderServiceServer: pb.UnimplementedOrderServiceServer{},
 		service:                         s,
-		accountClient:                   accountClient,
 		catalogClient:                   catalogClient,
 	})
 	reflection.Register(serv)
 	return serv.Serve(lis)
 }
 
-func (s *grpcServer) PostProduct(ctx context.Context, r *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
-	_, err := s.accountClient.GetAccount(ctx, r.AccountId)
+func (s *grpcServer) PostOrder(ctx context.Context, r *pb.PostOrderRequest) (*pb.PostOrderResponse, error) {
+	quantities := map[string]uint64{}
+	for _, rp := range r.Products {
+		quantities[rp.ProductId] += rp.Quantity
+	}
+
+	products, err := s.orderLines(ctx, quantities)
 	if err != nil {
-		log.Println("Error getting account: ", err)
 		return nil, err
 	}
 
+	order, err := s.service.PostOrder(ctx, r.AccountId, products, r.Coupons, addressFromProto(r.ShippingAddress))
+	if rejectsOrder(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Println("Error creating order: ", err)
+		return nil, errors.New("error creating order")
+	}
+	orderProto, err := orderToProto(order)
+	if err != nil {
+		log.Println("Error marshalling time: ", err)
+		return nil, errors.New("error marshalling time")
+	}
+	return &pb.PostOrderResponse{Order: orderProto}, nil
+
+}
+
+func addressFromProto(a *pb.Address) Address {
+	if a == nil {
+		return Address{}
+	}
+	return Address{Country: a.Country, Region: a.Region}
+}
+
+// rejectsOrder tells errors the customer can act on from failures of the
+// service.
+func rejectsOrder(err error) bool {
+	for _, reason := range []error{ErrEmptyOrder, ErrProductNotFound, ErrProductUnavailable, ErrOutOfStock, ErrInvalidAddress, ErrCouponNotFound, ErrCouponNotValid, ErrCouponLimitReached, ErrCouponNotStackable, ErrCouponNotApplicable} {
+		if errors.Is(err, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderLines prices the given quantities of products at their catalog
+// price, with bundles expanded into their components. Zero quantities are
+// left out, every other product must exist and be published.
+func (s *grpcServer) orderLines(ctx context.Context, quantities map[string]uint64) ([]OrderedProduct, error) {
 	productsID := []string{}
+	for id, quantity := range quantities {
+		if quantity != 0 {
+			productsID = append(productsID, id)
+		}
+	}
+	if len(productsID) == 0 {
+		return nil, ErrEmptyOrder
+	}
 
-	orderedProducts, err := s.catalogClient.GetProducts(ctx, 0, 0, "", productsID)
+	orderedProducts, err := s.catalogClient.GetAllProducts(ctx, 0, 0, "", productsID)
 	if err != nil {
 		log.Println("Error getting products: ", err)
 		return nil, errors.New("product not found")
 	}
 
+	found := map[string]bool{}
+	for _, p := range orderedProducts {
+		found[p.ID] = true
+	}
+	for _, id := range productsID {
+		if !found[id] {
+			return nil, fmt.Errorf("%w: %s", ErrProductNotFound, id)
+		}
+	}
+
 	products := []OrderedProduct{}
+	bundles := map[string]*catalog.Bundle{}
 	for _, p := range orderedProducts {
+		if p.Status != catalog.StatusPublished {
+			return nil, fmt.Errorf("%w: %s", ErrProductUnavailable, p.ID)
+		}
 		product := OrderedProduct{
 			ID:          p.ID,
-			Quantity:    0,
+			Quantity:    quantities[p.ID],
 			Price:       p.Price,
 			Name:        p.Name,
 			Description: p.Description,
 		}
-
-		for _, rp := range r.Products {
-			if rp.ProductId == p.ID {
-				product.Quantity = rp.Quantity
-				break
-			}
+		if class, ok := p.Attributes["tax_class"].(string); ok {
+			product.TaxClass = class
 		}
 
-		if product.Quantity == 0 {
-			products = append(products, product)
+		products = append(products, product)
+		if p.Bundle != nil {
+			bundles[p.ID] = p.Bundle
 		}
 	}
 
-	order, err := s.service.PostOrder(ctx, r.AccountId, products)
-	if err != nil {
-		log.Println("Error creating order: ", err)
-		return nil, errors.New("error creating order")
+	if err := expandBundles(ctx, s.catalogClient, products, bundles); err != nil {
+		log.Println("Error expanding bundles: ", err)
+		return nil, errors.New("error pricing bundles")
 	}
-	orderProto := &pb.Order{
-		Id:         order.ID,
-		AccountId:  order.AccountID,
-		TotalPrice: order.TotalPrice,
-		Products:   []*pb.Order_OrderedProduct{},
+	return products, nil
+}
+
+func (s *grpcServer) GetOrderForAccount(ctx context.Context, r *pb.GetOrderForAccountRequest) (*pb.GetOrderForAccountResponse, error) {
+	accountOrders, err := s.service.GetOrdersForAccount(ctx, r.AccountId)
+	if err != nil {
+		log.Println("Error getting order: ", err)
+		return nil, errors.New("error getting order")
 	}
-	orderProto.CreatedAt, err = order.CreatedAt.MarshalBinary()
+
+	orders, err := s.ordersToProto(ctx, accountOrders)
 	if err != nil {
-		log.Println("Error marshalling time: ", err)
-		return nil, errors.New("error marshalling time")
+		return nil, err
 	}
-	for _, p := range order.Products {
-		orderProto.Products = append(orderProto.Products, &pb.Order_OrderedProduct{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			Quantity:    p.Quantity,
-		})
+	return &pb.GetOrderForAccountResponse{Orders: orders}, nil
+}
+
+func (s *grpcServer) GetOrder(ctx context.Context, r *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
+	o, err := s.service.GetOrder(ctx, r.Id)
+	if err != nil {
+		log.Println("Error getting order: ", err)
+		return nil, err
 	}
-	return &pb.PostOrderResponse{Order: orderProto}, nil
 
+	orders, err := s.ordersToProto(ctx, []*Order{o})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetOrderResponse{Order: orders[0]}, nil
 }
 
-func (s *grpcServer) GetOrderForAccount(ctx context.Context, r *pb.GetOrderForAccountRequest) (*pb.GetOrderForAccountResponse, error) {
-	accountOrders, err := s.service.GetOrdersForAccount(ctx, r.AccountId)
+func (s *grpcServer) ListOrders(ctx context.Context, r *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	filter := OrderFilter{
+		AccountID: r.AccountId,
+		MinTotal:  r.MinTotal,
+		MaxTotal:  r.MaxTotal,
+		ProductID: r.ProductId,
+	}
+	for _, status := range r.Statuses {
+		filter.Statuses = append(filter.Statuses, OrderStatus(status))
+	}
+	var err error
+	if filter.CreatedAfter, err = optionalTimeFromProto(r.CreatedAfter); err != nil {
+		return nil, ErrInvalidFilter
+	}
+	if filter.CreatedBefore, err = optionalTimeFromProto(r.CreatedBefore); err != nil {
+		return nil, ErrInvalidFilter
+	}
+
+	res, err := s.service.GetOrders(ctx, filter, r.Skip, r.Take)
 	if err != nil {
-		log.Println("Error getting order: ", err)
-		return nil, errors.New("error getting order")
+		log.Println("Error listing orders: ", err)
+		return nil, err
+	}
+
+	list := []*Order{}
+	for i := range res {
+		list = append(list, &res[i])
+	}
+	orders, err := s.ordersToProto(ctx, list)
+	if err != nil {
+		return nil, err
 	}
+	return &pb.ListOrdersResponse{Orders: orders}, nil
+}
 
+// ordersToProto enriches the lines of orders from the catalog and converts
+// them.
+func (s *grpcServer) ordersToProto(ctx context.Context, orders []*Order) ([]*pb.Order, error) {
 	productIDMap := map[string]bool{}
-	for _, o := range accountOrders {
+	for _, o := range orders {
 		for _, p := range o.Products {
 			productIDMap[p.ID] = true
 		}
@@ -138,45 +228,597 @@ func (s *grpcServer) GetOrderForAccount(ctx context.Context, r *pb.GetOrderForAc
 		productIDs = append(productIDs, k)
 	}
 
-	products, err := s.catalogClient.GetProducts(ctx, 0, 0, "", productIDs)
-	if err != nil {
-		log.Println("Error getting products: ", err)
-		return nil, errors.New("error getting products")
+	products := []catalog.Product{}
+	if len(productIDs) > 0 {
+		var err error
+		if products, err = s.catalogClient.GetAllProducts(ctx, 0, 0, "", productIDs); err != nil {
+			log.Println("Error getting products: ", err)
+			return nil, errors.New("error getting products")
+		}
 	}
 
-	orders := []*pb.Order{}
-	for _, o := range accountOrders {
-		op := &pb.Order{
-			Id:         o.ID,
-			AccountId:  o.AccountID,
-			TotalPrice: o.TotalPrice,
-			Products:   []*pb.Order_OrderedProduct{},
-		}
-		op.CreatedAt, err = o.CreatedAt.MarshalBinary()
+	res := []*pb.Order{}
+	for _, o := range orders {
+		enrichLines(o.Products, products)
+
+		op, err := orderToProto(o)
 		if err != nil {
 			log.Println("Error marshalling time: ", err)
 			return nil, errors.New("error marshalling time")
 		}
+		res = append(res, op)
+	}
+	return res, nil
+}
+
+// optionalTimeFromProto reads a time that may be left empty.
+func optionalTimeFromProto(data []byte) (*time.Time, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	t := time.Time{}
+	if err := t.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// enrichLines adds catalog details to order lines. Lines keep the name,
+// description and price they were ordered with, only lines of orders from
+// before snapshots take them from the catalog.
+func enrichLines(lines []OrderedProduct, products []catalog.Product) {
+	byID := map[string]catalog.Product{}
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	for i, line := range lines {
+		p, ok := byID[line.ID]
+		if !ok {
+			continue
+		}
+		if len(p.Images) > 0 {
+			lines[i].ImageURL = p.Images[0].URL
+		}
+		if line.Name == "" {
+			lines[i].Name = p.Name
+			lines[i].Description = p.Description
+			lines[i].Price = p.Price
+		}
+	}
+}
 
-		for _, product := range o.Products {
-			for _, p := range products {
-				if p.ID == product.ID {
-					product.Name = p.Name
-					product.Description = p.Description
-					product.Price = p.Price
-					break
-				}
+func orderToProto(o *Order) (*pb.Order, error) {
+	op := &pb.Order{
+		Id:         o.ID,
+		AccountId:  o.AccountID,
+		TotalPrice: o.TotalPrice,
+		Products:   []*pb.Order_OrderedProduct{},
+		Status:     string(o.Status),
+		History:    []*pb.StatusChange{},
+		Refunds:    []*pb.Refund{},
+		NetTotal:   o.NetTotal(),
+		Discounts:  []*pb.Order_Discount{},
+		Subtotal:   o.Subtotal(),
+		ShippingAddress: &pb.Address{
+			Country: o.Address.Country,
+			Region:  o.Address.Region,
+		},
+		TaxMode:  string(o.TaxMode),
+		TaxLines: []*pb.Order_TaxLine{},
+		TaxTotal: o.TaxTotal(),
+	}
+	for _, t := range o.TaxLines {
+		op.TaxLines = append(op.TaxLines, &pb.Order_TaxLine{
+			ProductId:    t.ProductID,
+			TaxClass:     t.TaxClass,
+			Jurisdiction: t.Jurisdiction,
+			Rate:         t.Rate,
+			Taxable:      t.Taxable,
+			Amount:       t.Amount,
+		})
+	}
+	for _, d := range o.Discounts {
+		op.Discounts = append(op.Discounts, &pb.Order_Discount{ProductId: d.ProductID, Code: d.Code, Amount: d.Amount})
+	}
 
-			}
-			op.Products = append(op.Products, &pb.Order_OrderedProduct{
-				Id:          product.ID,
-				Name:        product.Name,
-				Description: product.Description,
-				Price:       product.Price,
-				Quantity:    product.Quantity,
-			})
+	var err error
+	if op.CreatedAt, err = o.CreatedAt.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	for _, p := range o.Products {
+		op.Products = append(op.Products, &pb.Order_OrderedProduct{
+			Id:          p.ID,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price,
+			Quantity:    p.Quantity,
+			Components:  componentsToProto(p.Components),
+			Currency:    p.Currency,
+			ImageUrl:    p.ImageURL,
+		})
+	}
+	for _, r := range o.Refunds {
+		rp, err := refundToProto(r)
+		if err != nil {
+			return nil, err
 		}
-		orders = append(orders, op)
+		op.Refunds = append(op.Refunds, rp)
 	}
-	return &pb.GetOrderForAccountResponse{Orders: orders}, nil
+	for _, c := range o.History {
+		createdAt, err := c.CreatedAt.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		op.History = append(op.History, &pb.StatusChange{
+			From:      string(c.From),
+			To:        string(c.To),
+			Actor:     c.Actor,
+			CreatedAt: createdAt,
+		})
+	}
+	return op, nil
+}
+
+func refundToProto(r Refund) (*pb.Refund, error) {
+	createdAt, err := r.CreatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &pb.Refund{
+		Id:        r.ID,
+		Amount:    r.Amount,
+		Reason:    r.Reason,
+		Actor:     r.Actor,
+		Lines:     []*pb.Refund_Line{},
+		CreatedAt: createdAt,
+	}
+	for _, l := range r.Lines {
+		rp.Lines = append(rp.Lines, &pb.Refund_Line{ProductId: l.ProductID, Quantity: l.Quantity, Amount: l.Amount})
+	}
+	return rp, nil
+}
+
+func componentsToProto(components []OrderedComponent) []*pb.Order_Component {
+	res := []*pb.Order_Component{}
+	for _, c := range components {
+		res = append(res, &pb.Order_Component{ProductId: c.ProductID, Quantity: c.Quantity})
+	}
+	return res
+}
+
+func (s *grpcServer) GetCoPurchasedProducts(ctx context.Context, r *pb.GetCoPurchasedProductsRequest) (*pb.GetCoPurchasedProductsResponse, error) {
+	res, err := s.service.GetCoPurchasedProducts(ctx, r.ProductId, r.Limit)
+	if err != nil {
+		log.Println("Error getting co-purchased products: ", err)
+		return nil, errors.New("error getting co-purchased products")
+	}
+
+	products := []*pb.GetCoPurchasedProductsResponse_CoPurchase{}
+	for _, p := range res {
+		products = append(products, &pb.GetCoPurchasedProductsResponse_CoPurchase{
+			ProductId: p.ProductID,
+			Count:     p.Count,
+		})
+	}
+	return &pb.GetCoPurchasedProductsResponse{Products: products}, nil
+}
+
+func (s *grpcServer) GetOrderedProducts(ctx context.Context, r *pb.GetOrderedProductsRequest) (*pb.GetOrderedProductsResponse, error) {
+	res, err := s.service.GetOrderedProducts(ctx, r.After, r.Take)
+	if err != nil {
+		log.Println("Error getting ordered products: ", err)
+		return nil, errors.New("error getting ordered products")
+	}
+
+	products := []*pb.GetOrderedProductsResponse_ProductReference{}
+	for _, p := range res {
+		products = append(products, &pb.GetOrderedProductsResponse_ProductReference{
+			ProductId:   p.ProductID,
+			Orders:      p.Orders,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price,
+		})
+	}
+	return &pb.GetOrderedProductsResponse{Products: products}, nil
+}
+
+func (s *grpcServer) UpdateOrderStatus(ctx context.Context, r *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
+	o, err := s.service.UpdateOrderStatus(ctx, r.Id, OrderStatus(r.Status), r.Actor)
+	if err != nil {
+		log.Println("Error updating order status: ", err)
+		return nil, err
+	}
+
+	op, err := orderToProto(o)
+	if err != nil {
+		log.Println("Error marshalling time: ", err)
+		return nil, errors.New("error marshalling time")
+	}
+	return &pb.UpdateOrderStatusResponse{Order: op}, nil
+}
+
+func (s *grpcServer) CancelOrder(ctx context.Context, r *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	o, err := s.service.CancelOrder(ctx, r.Id, r.AccountId, r.Actor, r.Reason)
+	if err != nil {
+		log.Println("Error cancelling order: ", err)
+		return nil, err
+	}
+
+	op, err := orderToProto(o)
+	if err != nil {
+		log.Println("Error marshalling time: ", err)
+		return nil, errors.New("error marshalling time")
+	}
+	return &pb.CancelOrderResponse{Order: op}, nil
+}
+
+func (s *grpcServer) RefundOrder(ctx context.Context, r *pb.RefundOrderRequest) (*pb.RefundOrderResponse, error) {
+	lines := []RefundLine{}
+	for _, l := range r.Lines {
+		lines = append(lines, RefundLine{ProductID: l.ProductId, Quantity: l.Quantity})
+	}
+
+	o, err := s.service.RefundOrder(ctx, r.Id, lines, r.Actor, r.Reason)
+	if err != nil {
+		log.Println("Error refunding order: ", err)
+		return nil, err
+	}
+
+	op, err := orderToProto(o)
+	if err != nil {
+		log.Println("Error marshalling time: ", err)
+		return nil, errors.New("error marshalling time")
+	}
+	return &pb.RefundOrderResponse{Order: op}, nil
+}
+
+// GetOrderSaga shows how placing an order went, step by step.
+func (s *grpcServer) GetOrderSaga(ctx context.Context, r *pb.GetOrderSagaRequest) (*pb.GetOrderSagaResponse, error) {
+	saga, err := s.service.GetOrderSaga(ctx, r.Id)
+	if err != nil {
+		log.Println("Error getting order saga: ", err)
+		return nil, err
+	}
+
+	sagaProto, err := sagaToProto(saga)
+	if err != nil {
+		log.Println("Error marshalling time: ", err)
+		return nil, errors.New("error marshalling time")
+	}
+	return &pb.GetOrderSagaResponse{Saga: sagaProto}, nil
+}
+
+func sagaToProto(saga *OrderSaga) (*pb.OrderSaga, error) {
+	orderProto, err := orderToProto(&saga.Order)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := saga.CreatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := saga.UpdatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &pb.OrderSaga{
+		Id:              saga.ID,
+		Status:          string(saga.Status),
+		Order:           orderProto,
+		Steps:           []*pb.OrderSaga_Step{},
+		AuthorizationId: saga.AuthorizationID,
+		Error:           saga.Error,
+		CreatedAt:       createdAt,
+		UpdatedAt:       updatedAt,
+	}
+	for _, step := range saga.Steps {
+		stepUpdatedAt, err := step.UpdatedAt.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		sp.Steps = append(sp.Steps, &pb.OrderSaga_Step{
+			Name:      step.Name,
+			Status:    string(step.Status),
+			Error:     step.Error,
+			UpdatedAt: stepUpdatedAt,
+		})
+	}
+	return sp, nil
+}
+
+// priceCart looks up the lines of a cart in the catalog. Lines of products
+// that are gone or not published are not available.
+func (s *grpcServer) priceCart(ctx context.Context, c *Cart) error {
+	if len(c.Lines) == 0 {
+		return nil
+	}
+
+	productsID := []string{}
+	for _, l := range c.Lines {
+		productsID = append(productsID, l.ProductID)
+	}
+	products, err := s.catalogClient.GetProducts(ctx, 0, 0, "", productsID)
+	if err != nil {
+		return err
+	}
+
+	byID := map[string]catalog.Product{}
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	for i, l := range c.Lines {
+		p, ok := byID[l.ProductID]
+		c.Lines[i].Available = ok && p.Status == catalog.StatusPublished
+		if ok {
+			c.Lines[i].Name = p.Name
+			c.Lines[i].Price = p.Price
+		}
+	}
+	return nil
+}
+
+func (s *grpcServer) cartToProto(ctx context.Context, c *Cart) (*pb.Cart, error) {
+	if err := s.priceCart(ctx, c); err != nil {
+		log.Println("Error pricing cart: ", err)
+		return nil, errors.New("error pricing cart")
+	}
+
+	createdAt, err := c.CreatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := c.UpdatedAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := c.ExpiresAt.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &pb.Cart{
+		Id:        c.ID,
+		AccountId: c.AccountID,
+		Token:     c.Token,
+		Lines:     []*pb.Cart_Line{},
+		Total:     c.Total(),
+		Currency:  DefaultCurrency,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		ExpiresAt: expiresAt,
+	}
+	for _, l := range c.Lines {
+		cp.Lines = append(cp.Lines, &pb.Cart_Line{
+			ProductId: l.ProductID,
+			Quantity:  l.Quantity,
+			Name:      l.Name,
+			Price:     l.Price,
+			Available: l.Available,
+		})
+	}
+	return cp, nil
+}
+
+func (s *grpcServer) GetCart(ctx context.Context, r *pb.GetCartRequest) (*pb.GetCartResponse, error) {
+	c, err := s.service.GetCart(ctx, CartOwner{AccountID: r.AccountId, Token: r.Token})
+	if err != nil {
+		log.Println("Error getting cart: ", err)
+		return nil, err
+	}
+
+	cart, err := s.cartToProto(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetCartResponse{Cart: cart}, nil
+}
+
+func (s *grpcServer) AddCartLine(ctx context.Context, r *pb.AddCartLineRequest) (*pb.AddCartLineResponse, error) {
+	c, err := s.service.AddCartLine(ctx, CartOwner{AccountID: r.AccountId, Token: r.Token}, r.ProductId, r.Quantity)
+	if err != nil {
+		log.Println("Error adding cart line: ", err)
+		return nil, err
+	}
+
+	cart, err := s.cartToProto(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AddCartLineResponse{Cart: cart}, nil
+}
+
+func (s *grpcServer) UpdateCartLine(ctx context.Context, r *pb.UpdateCartLineRequest) (*pb.UpdateCartLineResponse, error) {
+	c, err := s.service.UpdateCartLine(ctx, CartOwner{AccountID: r.AccountId, Token: r.Token}, r.ProductId, r.Quantity)
+	if err != nil {
+		log.Println("Error updating cart line: ", err)
+		return nil, err
+	}
+
+	cart, err := s.cartToProto(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateCartLineResponse{Cart: cart}, nil
+}
+
+func (s *grpcServer) RemoveCartLine(ctx context.Context, r *pb.RemoveCartLineRequest) (*pb.RemoveCartLineResponse, error) {
+	c, err := s.service.RemoveCartLine(ctx, CartOwner{AccountID: r.AccountId, Token: r.Token}, r.ProductId)
+	if err != nil {
+		log.Println("Error removing cart line: ", err)
+		return nil, err
+	}
+
+	cart, err := s.cartToProto(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RemoveCartLineResponse{Cart: cart}, nil
+}
+
+func (s *grpcServer) MergeCart(ctx context.Context, r *pb.MergeCartRequest) (*pb.MergeCartResponse, error) {
+	c, err := s.service.MergeCart(ctx, r.Token, r.AccountId)
+	if err != nil {
+		log.Println("Error merging cart: ", err)
+		return nil, err
+	}
+
+	cart, err := s.cartToProto(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.MergeCartResponse{Cart: cart}, nil
+}
+
+// CheckoutCart places an order for everything in the cart of an account at
+// current catalog prices, then empties the cart.
+func (s *grpcServer) CheckoutCart(ctx context.Context, r *pb.CheckoutCartRequest) (*pb.CheckoutCartResponse, error) {
+	if r.AccountId == "" {
+		return nil, ErrCartNotFound
+	}
+
+	var c *Cart
+	var err error
+	if r.Token != "" {
+		c, err = s.service.MergeCart(ctx, r.Token, r.AccountId)
+	} else {
+		c, err = s.service.GetCart(ctx, CartOwner{AccountID: r.AccountId})
+	}
+	if err != nil {
+		log.Println("Error getting cart: ", err)
+		return nil, err
+	}
+	if len(c.Lines) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	if err := s.priceCart(ctx, c); err != nil {
+		log.Println("Error pricing cart: ", err)
+		return nil, errors.New("error pricing cart")
+	}
+	quantities := map[string]uint64{}
+	for _, l := range c.Lines {
+		if !l.Available {
+			return nil, fmt.Errorf("%w %s is not available", ErrInvalidCartLine, l.ProductID)
+		}
+		quantities[l.ProductID] = l.Quantity
+	}
+
+	products, err := s.orderLines(ctx, quantities)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.service.PostOrder(ctx, r.AccountId, products, r.Coupons, addressFromProto(r.ShippingAddress))
+	if rejectsOrder(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Println("Error creating order: ", err)
+		return nil, errors.New("error creating order")
+	}
+
+	if err := s.service.ClearCart(ctx, c.ID); err != nil {
+		log.Println("Error clearing cart: ", err)
+	}
+
+	orderProto, err := orderToProto(order)
+	if err != nil {
+		log.Println("Error marshalling time: ", err)
+		return nil, errors.New("error marshalling time")
+	}
+	return &pb.CheckoutCartResponse{Order: orderProto}, nil
+}
+
+func (s *grpcServer) CreatePromotion(ctx context.Context, r *pb.CreatePromotionRequest) (*pb.CreatePromotionResponse, error) {
+	p, err := promotionFromProto(r.Promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.service.CreatePromotion(ctx, *p)
+	if err != nil {
+		log.Println("Error creating promotion: ", err)
+		return nil, err
+	}
+
+	promotion, err := promotionToProto(created)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreatePromotionResponse{Promotion: promotion}, nil
+}
+
+func (s *grpcServer) GetPromotion(ctx context.Context, r *pb.GetPromotionRequest) (*pb.GetPromotionResponse, error) {
+	p, err := s.service.GetPromotion(ctx, r.Code)
+	if err != nil {
+		log.Println("Error getting promotion: ", err)
+		return nil, err
+	}
+
+	promotion, err := promotionToProto(p)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetPromotionResponse{Promotion: promotion}, nil
+}
+
+func promotionToProto(p *Promotion) (*pb.Promotion, error) {
+	pp := &pb.Promotion{
+		Code:              p.Code,
+		Kind:              string(p.Kind),
+		Value:             p.Value,
+		ProductId:         p.ProductID,
+		BuyQuantity:       p.BuyQuantity,
+		GetQuantity:       p.GetQuantity,
+		Stackable:         p.Stackable,
+		MaxUses:           p.MaxUses,
+		MaxUsesPerAccount: p.MaxUsesPerAccount,
+	}
+
+	var err error
+	if pp.StartsAt, err = optionalTimeToProto(p.StartsAt); err != nil {
+		return nil, err
+	}
+	if pp.EndsAt, err = optionalTimeToProto(p.EndsAt); err != nil {
+		return nil, err
+	}
+	if pp.CreatedAt, err = p.CreatedAt.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+func promotionFromProto(pp *pb.Promotion) (*Promotion, error) {
+	if pp == nil {
+		return nil, ErrInvalidPromotion
+	}
+
+	p := &Promotion{
+		Code:              pp.Code,
+		Kind:              PromotionKind(pp.Kind),
+		Value:             pp.Value,
+		ProductID:         pp.ProductId,
+		BuyQuantity:       pp.BuyQuantity,
+		GetQuantity:       pp.GetQuantity,
+		Stackable:         pp.Stackable,
+		MaxUses:           pp.MaxUses,
+		MaxUsesPerAccount: pp.MaxUsesPerAccount,
+	}
+
+	var err error
+	if p.StartsAt, err = optionalTimeFromProto(pp.StartsAt); err != nil {
+		return nil, err
+	}
+	if p.EndsAt, err = optionalTimeFromProto(pp.EndsAt); err != nil {
+		return nil, err
+	}
+	if len(pp.CreatedAt) > 0 {
+		if err = p.CreatedAt.UnmarshalBinary(pp.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
 }
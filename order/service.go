@@ -2,23 +2,55 @@ package order
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/segmentio/ksuid"
 )
 
+var (
+	ErrEmptyOrder         = errors.New("Order has no lines")
+	ErrProductNotFound    = errors.New("Product not found")
+	ErrProductUnavailable = errors.New("Product is not available")
+)
+
 type Service interface {
-	PostOrder(ctx context.Context, accountID string, products []OrderedProduct) (*Order, error)
+	PostOrder(ctx context.Context, accountID string, products []OrderedProduct, coupons []string, address Address) (*Order, error)
 	GetOrdersForAccount(ctx context.Context, id string) ([]*Order, error)
-	GetOrders(ctx context.Context, skip uint64, take uint64) ([]Order, error)
+	GetOrder(ctx context.Context, id string) (*Order, error)
+	GetOrders(ctx context.Context, filter OrderFilter, skip uint64, take uint64) ([]Order, error)
+	GetCoPurchasedProducts(ctx context.Context, productID string, limit uint64) ([]CoPurchase, error)
+	GetOrderedProducts(ctx context.Context, after string, take uint64) ([]ProductReference, error)
+	UpdateOrderStatus(ctx context.Context, id string, status OrderStatus, actor string) (*Order, error)
+	CancelOrder(ctx context.Context, id string, accountID string, actor string, reason string) (*Order, error)
+	RefundOrder(ctx context.Context, id string, lines []RefundLine, actor string, reason string) (*Order, error)
+	GetOrderSaga(ctx context.Context, id string) (*OrderSaga, error)
+	RunSagaRecovery(ctx context.Context, interval time.Duration)
+	GetCart(ctx context.Context, owner CartOwner) (*Cart, error)
+	AddCartLine(ctx context.Context, owner CartOwner, productID string, quantity uint64) (*Cart, error)
+	UpdateCartLine(ctx context.Context, owner CartOwner, productID string, quantity uint64) (*Cart, error)
+	RemoveCartLine(ctx context.Context, owner CartOwner, productID string) (*Cart, error)
+	MergeCart(ctx context.Context, token string, accountID string) (*Cart, error)
+	ClearCart(ctx context.Context, id string) error
+	RunCartCleanup(ctx context.Context, interval time.Duration)
+	CreatePromotion(ctx context.Context, p Promotion) (*Promotion, error)
+	GetPromotion(ctx context.Context, code string) (*Promotion, error)
 }
 
 type Order struct {
-	ID         string    `json:"id"`
-	CreatedAt  time.Time `json:"created_at"`
-	AccountID  string    `json:"account_id"`
-	TotalPrice float64   `json:"total_price"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	AccountID string    `json:"account_id"`
+	// TotalPrice is what the order costs after discounts, with tax.
+	TotalPrice float64 `json:"total_price"`
 	Products   []OrderedProduct
+	Status     OrderStatus    `json:"status"`
+	History    []StatusChange `json:"history"`
+	Refunds    []Refund       `json:"refunds"`
+	Discounts  []Discount     `json:"discounts"`
+	Address    Address        `json:"address"`
+	TaxMode    TaxMode        `json:"tax_mode"`
+	TaxLines   []TaxLine      `json:"tax_lines"`
 }
 
 type OrderedProduct struct {
@@ -27,28 +59,97 @@ type OrderedProduct struct {
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
 	Quantity    uint64  `json:"quantity"`
+	Currency    string  `json:"currency"`
+	// ImageURL is looked up in the catalog when the order is read, it is
+	// not part of the snapshot.
+	ImageURL string `json:"image_url,omitempty"`
+	// Components lists what ships for a bundle line, empty otherwise.
+	Components []OrderedComponent `json:"components,omitempty"`
+	// TaxClass comes from the catalog when the order is placed, the tax
+	// lines keep it.
+	TaxClass string `json:"tax_class,omitempty"`
+}
+
+// CoPurchase counts the orders in which a product was bought together with
+// another one.
+type CoPurchase struct {
+	ProductID string `json:"product_id"`
+	Count     uint64 `json:"count"`
+}
+
+// ProductReference counts the orders that contain a product. Name,
+// Description and Price come from the latest order line snapshot of it.
+type ProductReference struct {
+	ProductID   string  `json:"product_id"`
+	Orders      uint64  `json:"orders"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
 }
 
+// DefaultCurrency is the currency of catalog prices.
+const DefaultCurrency = "USD"
+
 type orderService struct {
 	repository Repository
+	inventory  Inventory
+	accounts   Accounts
+	payments   Payments
+	tax        TaxCalculator
+	taxMode    TaxMode
 }
 
-func NewService(r Repository) *orderService {
-	return &orderService{r}
+// NewService creates the order service, inv may be nil when stock is not
+// tracked and tax nil when orders are not taxed.
+func NewService(r Repository, inv Inventory, accounts Accounts, payments Payments, tax TaxCalculator, taxMode TaxMode) *orderService {
+	if taxMode == "" {
+		taxMode = TaxExclusive
+	}
+	return &orderService{r, inv, accounts, payments, tax, taxMode}
 }
 
-func (s *orderService) PostOrder(ctx context.Context, accountID string, products []OrderedProduct) (*Order, error) {
+// PostOrder places an order through a saga, see runSaga. When placing
+// fails the steps already taken are undone before the error is returned.
+// The coupons are applied to the lines before the order is placed, then
+// taxes for the address.
+func (s *orderService) PostOrder(ctx context.Context, accountID string, products []OrderedProduct, coupons []string, address Address) (*Order, error) {
 	o := &Order{
 		ID:         ksuid.New().String(),
 		AccountID:  accountID,
 		CreatedAt:  time.Now().UTC(),
 		TotalPrice: 0.0,
 		Products:   products,
+		Status:     StatusPending,
+		Discounts:  []Discount{},
+		Address:    address,
+	}
+	o.History = []StatusChange{{To: StatusPending, Actor: accountID, CreatedAt: o.CreatedAt}}
+	for i, p := range products {
+		if p.Currency == "" {
+			o.Products[i].Currency = DefaultCurrency
+		}
+	}
+
+	if err := address.validate(); err != nil {
+		return nil, err
+	}
+
+	promotions, err := s.promotions(ctx, accountID, coupons, o.CreatedAt)
+	if err != nil {
+		return nil, err
 	}
-	for _, p := range products {
-		o.TotalPrice += p.Price * float64(p.Quantity)
+	if o.Discounts, err = applyPromotions(o.Products, promotions); err != nil {
+		return nil, err
 	}
-	if err := s.repository.PutOrder(ctx, *o); err != nil {
+	if err = s.applyTaxes(ctx, o); err != nil {
+		return nil, err
+	}
+
+	saga := newOrderSaga(*o)
+	if err := s.repository.PutSaga(ctx, *saga); err != nil {
+		return nil, err
+	}
+	if err := s.runSaga(ctx, saga); err != nil {
 		return nil, err
 	}
 
@@ -59,9 +160,31 @@ func (s *orderService) GetOrdersForAccount(ctx context.Context, id string) ([]*O
 	return s.repository.GetOrderForAccount(ctx, id)
 }
 
-func (s *orderService) GetOrders(ctx context.Context, skip uint64, take uint64) ([]Order, error) {
-	if skip > 100 || (take == 0 && skip == 0) {
+func (s *orderService) GetOrder(ctx context.Context, id string) (*Order, error) {
+	return s.repository.GetOrder(ctx, id)
+}
+
+// GetOrders pages through the orders matching filter, newest first.
+func (s *orderService) GetOrders(ctx context.Context, filter OrderFilter, skip uint64, take uint64) ([]Order, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	if take == 0 || take > 100 {
 		take = 100
 	}
-	return s.repository.ListOrders(ctx, skip, take)
+	return s.repository.ListOrders(ctx, filter, skip, take)
+}
+
+func (s *orderService) GetCoPurchasedProducts(ctx context.Context, productID string, limit uint64) ([]CoPurchase, error) {
+	if limit == 0 || limit > 100 {
+		limit = 100
+	}
+	return s.repository.GetCoPurchasedProducts(ctx, productID, limit)
+}
+
+func (s *orderService) GetOrderedProducts(ctx context.Context, after string, take uint64) ([]ProductReference, error) {
+	if take == 0 || take > 1000 {
+		take = 1000
+	}
+	return s.repository.ListOrderedProducts(ctx, after, take)
 }
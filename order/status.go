@@ -0,0 +1,100 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type OrderStatus string
+
+const (
+	StatusPending   OrderStatus = "pending"
+	StatusPaid      OrderStatus = "paid"
+	StatusFulfilled OrderStatus = "fulfilled"
+	StatusShipped   OrderStatus = "shipped"
+	StatusDelivered OrderStatus = "delivered"
+	StatusCancelled OrderStatus = "cancelled"
+	StatusRefunded  OrderStatus = "refunded"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("Invalid order status")
+	ErrIllegalTransition = errors.New("Illegal order status transition")
+	ErrOrderNotFound     = errors.New("Order not found")
+	// ErrStatusConflict means the status changed while a transition was
+	// being applied.
+	ErrStatusConflict = errors.New("Order status changed concurrently")
+)
+
+// transitions lists the statuses each status may move to. Cancelled and
+// refunded orders are final.
+var transitions = map[OrderStatus][]OrderStatus{
+	StatusPending:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusFulfilled, StatusCancelled, StatusRefunded},
+	StatusFulfilled: {StatusShipped, StatusCancelled, StatusRefunded},
+	StatusShipped:   {StatusDelivered, StatusRefunded},
+	StatusDelivered: {StatusRefunded},
+	StatusCancelled: {},
+	StatusRefunded:  {},
+}
+
+func (s OrderStatus) valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanMoveTo reports whether an order may go from s to next.
+func (s OrderStatus) CanMoveTo(next OrderStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange records one transition of an order. From is empty for the
+// status the order was placed with.
+type StatusChange struct {
+	From      OrderStatus `json:"from"`
+	To        OrderStatus `json:"to"`
+	Actor     string      `json:"actor"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// UpdateOrderStatus moves an order to status on behalf of actor, rejecting
+// moves the state machine does not allow. Orders are only cancelled and
+// refunded through CancelOrder and RefundOrder, which also settle payment
+// and stock.
+func (s *orderService) UpdateOrderStatus(ctx context.Context, id string, status OrderStatus, actor string) (*Order, error) {
+	if !status.valid() {
+		return nil, ErrInvalidStatus
+	}
+	if status == StatusCancelled || status == StatusRefunded {
+		return nil, fmt.Errorf("%w to %s, cancel or refund the order instead", ErrIllegalTransition, status)
+	}
+
+	o, err := s.repository.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !o.Status.CanMoveTo(status) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrIllegalTransition, o.Status, status)
+	}
+
+	change := StatusChange{
+		From:      o.Status,
+		To:        status,
+		Actor:     actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repository.UpdateOrderStatus(ctx, id, change); err != nil {
+		return nil, err
+	}
+
+	o.Status = status
+	o.History = append(o.History, change)
+	return o, nil
+}
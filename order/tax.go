@@ -0,0 +1,226 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrInvalidTaxRate = errors.New("Invalid tax rate")
+	ErrInvalidAddress = errors.New("Invalid address")
+)
+
+// TaxMode tells whether catalog prices include tax.
+type TaxMode string
+
+const (
+	// TaxExclusive adds tax on top of prices.
+	TaxExclusive TaxMode = "exclusive"
+	// TaxInclusive takes tax out of prices, totals stay what the prices
+	// add up to.
+	TaxInclusive TaxMode = "inclusive"
+)
+
+// DefaultTaxClass is the tax class of products that do not set the
+// tax_class attribute in the catalog.
+const DefaultTaxClass = "standard"
+
+// Address is where an order ships, it decides which taxes apply. Country
+// is an ISO 3166 code, Region a subdivision of it such as a state.
+type Address struct {
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+}
+
+func (a Address) validate() error {
+	if a.Country == "" && a.Region == "" {
+		return nil
+	}
+	if len(a.Country) != 2 || len(a.Region) > 8 {
+		return ErrInvalidAddress
+	}
+	for _, c := range a.Country {
+		if !unicode.IsLetter(c) {
+			return ErrInvalidAddress
+		}
+	}
+	return nil
+}
+
+// TaxableLine is what one line of an order costs after discounts.
+type TaxableLine struct {
+	ProductID string
+	TaxClass  string
+	Amount    float64
+}
+
+// TaxLine is the tax on one line of an order.
+type TaxLine struct {
+	ProductID    string  `json:"product_id"`
+	TaxClass     string  `json:"tax_class"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Rate         float64 `json:"rate"`
+	Taxable      float64 `json:"taxable"`
+	Amount       float64 `json:"amount"`
+}
+
+// TaxCalculator works out the taxes of the lines of an order shipping to
+// address. Lines that are not taxed get no tax line.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, address Address, mode TaxMode, lines []TaxableLine) ([]TaxLine, error)
+}
+
+// TaxRate is one row of a TaxTable. An empty Country, Region or Class
+// matches any.
+type TaxRate struct {
+	Country string  `json:"country"`
+	Region  string  `json:"region"`
+	Class   string  `json:"class"`
+	Rate    float64 `json:"rate"`
+}
+
+// TaxTable is a TaxCalculator with fixed rates. The most specific rate
+// matching a line wins: region over country over any place, then a class
+// over any class.
+type TaxTable struct {
+	rates map[TaxRate]float64
+}
+
+func NewTaxTable(rates []TaxRate) (*TaxTable, error) {
+	t := &TaxTable{rates: map[TaxRate]float64{}}
+	for _, r := range rates {
+		if r.Rate < 0 || r.Rate >= 1 || (r.Region != "" && r.Country == "") {
+			return nil, fmt.Errorf("%w: %+v", ErrInvalidTaxRate, r)
+		}
+		rate := r.Rate
+		r.Rate = 0
+		r.Country = strings.ToUpper(r.Country)
+		r.Region = strings.ToUpper(r.Region)
+		r.Class = strings.ToLower(r.Class)
+		t.rates[r] = rate
+	}
+	return t, nil
+}
+
+// LoadTaxTable reads a TaxTable from a JSON array of rates, such as
+// [{"country": "US", "region": "CA", "class": "standard", "rate": 0.0725}].
+func LoadTaxTable(path string) (*TaxTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rates := []TaxRate{}
+	if err = json.Unmarshal(data, &rates); err != nil {
+		return nil, err
+	}
+	return NewTaxTable(rates)
+}
+
+// rate returns the rate of a class at address and the jurisdiction it
+// belongs to.
+func (t *TaxTable) rate(address Address, class string) (float64, string, bool) {
+	country := strings.ToUpper(address.Country)
+	region := strings.ToUpper(address.Region)
+	class = strings.ToLower(class)
+	places := []TaxRate{{Country: country, Region: region}, {Country: country}, {}}
+	if region == "" {
+		places = places[1:]
+	}
+
+	for _, place := range places {
+		for _, c := range []string{class, ""} {
+			key := place
+			key.Class = c
+			if rate, ok := t.rates[key]; ok {
+				jurisdiction := key.Country
+				if key.Region != "" {
+					jurisdiction += "-" + key.Region
+				}
+				return rate, jurisdiction, true
+			}
+		}
+	}
+	return 0, "", false
+}
+
+func (t *TaxTable) Calculate(ctx context.Context, address Address, mode TaxMode, lines []TaxableLine) ([]TaxLine, error) {
+	taxes := []TaxLine{}
+	for _, l := range lines {
+		rate, jurisdiction, ok := t.rate(address, l.TaxClass)
+		if !ok || rate == 0 || l.Amount <= 0 {
+			continue
+		}
+
+		amount := l.Amount * rate
+		if mode == TaxInclusive {
+			amount = l.Amount - l.Amount/(1+rate)
+		}
+		taxes = append(taxes, TaxLine{
+			ProductID:    l.ProductID,
+			TaxClass:     l.TaxClass,
+			Jurisdiction: jurisdiction,
+			Rate:         rate,
+			Taxable:      l.Amount,
+			Amount:       math.Round(amount*100) / 100,
+		})
+	}
+	return taxes, nil
+}
+
+// TaxTotal is the tax of the order, included in TotalPrice either way.
+func (o Order) TaxTotal() float64 {
+	total := 0.0
+	for _, t := range o.TaxLines {
+		total += t.Amount
+	}
+	return roundCents(total)
+}
+
+// lineTax is the tax of a line.
+func (o Order) lineTax(productID string) float64 {
+	total := 0.0
+	for _, t := range o.TaxLines {
+		if t.ProductID == productID {
+			total += t.Amount
+		}
+	}
+	return total
+}
+
+// applyTaxes works out the taxes of the order and its total. Without a
+// calculator nothing is taxed.
+func (s *orderService) applyTaxes(ctx context.Context, o *Order) error {
+	o.TaxMode = s.taxMode
+	o.TaxLines = []TaxLine{}
+	if s.tax != nil {
+		lines := []TaxableLine{}
+		for _, p := range o.Products {
+			class := p.TaxClass
+			if class == "" {
+				class = DefaultTaxClass
+			}
+			lines = append(lines, TaxableLine{
+				ProductID: p.ID,
+				TaxClass:  class,
+				Amount:    roundCents(p.Price*float64(p.Quantity) - o.lineDiscount(p.ID)),
+			})
+		}
+
+		var err error
+		if o.TaxLines, err = s.tax.Calculate(ctx, o.Address, o.TaxMode, lines); err != nil {
+			return err
+		}
+	}
+
+	o.TotalPrice = roundCents(o.Subtotal() - o.DiscountTotal())
+	if o.TaxMode == TaxExclusive {
+		o.TotalPrice = roundCents(o.TotalPrice + o.TaxTotal())
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package order
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTaxTableRate(t *testing.T) {
+	table, err := NewTaxTable([]TaxRate{
+		{Rate: 0.01},
+		{Class: "food", Rate: 0.02},
+		{Country: "US", Rate: 0.05},
+		{Country: "US", Class: "food", Rate: 0.03},
+		{Country: "US", Region: "CA", Rate: 0.0725},
+		{Country: "US", Region: "CA", Class: "food", Rate: 0.04},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		address      Address
+		class        string
+		rate         float64
+		jurisdiction string
+	}{
+		{name: "any place, any class", address: Address{Country: "DE"}, class: "standard", rate: 0.01, jurisdiction: ""},
+		{name: "any place, class", address: Address{Country: "DE"}, class: "food", rate: 0.02, jurisdiction: ""},
+		{name: "country over any place", address: Address{Country: "US"}, class: "standard", rate: 0.05, jurisdiction: "US"},
+		{name: "country class over country", address: Address{Country: "US"}, class: "food", rate: 0.03, jurisdiction: "US"},
+		{name: "region over country", address: Address{Country: "US", Region: "CA"}, class: "standard", rate: 0.0725, jurisdiction: "US-CA"},
+		{name: "region class over region", address: Address{Country: "US", Region: "CA"}, class: "food", rate: 0.04, jurisdiction: "US-CA"},
+		{name: "unknown region falls back to country", address: Address{Country: "US", Region: "NY"}, class: "food", rate: 0.03, jurisdiction: "US"},
+		{name: "case does not matter", address: Address{Country: "us", Region: "ca"}, class: "FOOD", rate: 0.04, jurisdiction: "US-CA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taxes, err := table.Calculate(context.Background(), tt.address, TaxExclusive, []TaxableLine{{ProductID: "a", TaxClass: tt.class, Amount: 100}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(taxes) != 1 {
+				t.Fatalf("got %d tax lines, want 1", len(taxes))
+			}
+			if taxes[0].Rate != tt.rate || taxes[0].Jurisdiction != tt.jurisdiction {
+				t.Errorf("rate = %v in %q, want %v in %q", taxes[0].Rate, taxes[0].Jurisdiction, tt.rate, tt.jurisdiction)
+			}
+		})
+	}
+}
+
+func TestTaxTableUntaxed(t *testing.T) {
+	table, err := NewTaxTable([]TaxRate{{Country: "US", Rate: 0.05}, {Country: "US", Class: "exempt", Rate: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taxes, err := table.Calculate(context.Background(), Address{Country: "US"}, TaxExclusive, []TaxableLine{
+		{ProductID: "exempt", TaxClass: "exempt", Amount: 10},
+		{ProductID: "free", TaxClass: "standard", Amount: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taxes) != 0 {
+		t.Errorf("got tax lines %+v, want none", taxes)
+	}
+
+	taxes, err = table.Calculate(context.Background(), Address{Country: "FR"}, TaxExclusive, []TaxableLine{{ProductID: "a", TaxClass: "standard", Amount: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taxes) != 0 {
+		t.Errorf("got tax lines %+v outside any jurisdiction, want none", taxes)
+	}
+}
+
+func TestApplyTaxes(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		mode      TaxMode
+		products  []OrderedProduct
+		discounts []Discount
+		taxes     []float64
+		total     float64
+	}{
+		{
+			name:     "exclusive adds tax",
+			rate:     0.2,
+			mode:     TaxExclusive,
+			products: []OrderedProduct{{ID: "a", Price: 12, Quantity: 1}},
+			taxes:    []float64{2.4},
+			total:    14.4,
+		},
+		{
+			name:     "inclusive takes tax out",
+			rate:     0.2,
+			mode:     TaxInclusive,
+			products: []OrderedProduct{{ID: "a", Price: 12, Quantity: 1}},
+			taxes:    []float64{2},
+			total:    12,
+		},
+		{
+			name:      "exclusive rounds after discounts",
+			rate:      0.0725,
+			mode:      TaxExclusive,
+			products:  []OrderedProduct{{ID: "a", Price: 9.99, Quantity: 3}},
+			discounts: []Discount{{ProductID: "a", Code: "P10", Amount: 3}},
+			taxes:     []float64{1.96},
+			total:     28.93,
+		},
+		{
+			name:      "inclusive rounds after discounts",
+			rate:      0.0725,
+			mode:      TaxInclusive,
+			products:  []OrderedProduct{{ID: "a", Price: 9.99, Quantity: 3}},
+			discounts: []Discount{{ProductID: "a", Code: "P10", Amount: 3}},
+			taxes:     []float64{1.82},
+			total:     26.97,
+		},
+		{
+			name:     "each line rounds on its own",
+			rate:     0.1,
+			mode:     TaxExclusive,
+			products: []OrderedProduct{{ID: "a", Price: 10, Quantity: 1}, {ID: "b", Price: 20, Quantity: 1}},
+			discounts: []Discount{
+				{ProductID: "a", Code: "F10", Amount: 3.33},
+				{ProductID: "b", Code: "F10", Amount: 6.67},
+			},
+			taxes: []float64{0.67, 1.33},
+			total: 22,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := NewTaxTable([]TaxRate{{Country: "US", Rate: tt.rate}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewService(nil, nil, nil, nil, table, tt.mode)
+			o := &Order{Products: tt.products, Discounts: tt.discounts, Address: Address{Country: "US"}}
+			if err = s.applyTaxes(context.Background(), o); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.TaxMode != tt.mode {
+				t.Errorf("tax mode = %s, want %s", o.TaxMode, tt.mode)
+			}
+			if len(o.TaxLines) != len(tt.taxes) {
+				t.Fatalf("got %d tax lines, want %d", len(o.TaxLines), len(tt.taxes))
+			}
+			for i, tax := range tt.taxes {
+				if o.TaxLines[i].Amount != tax {
+					t.Errorf("tax of %s = %v, want %v", o.TaxLines[i].ProductID, o.TaxLines[i].Amount, tax)
+				}
+			}
+			if o.TotalPrice != tt.total {
+				t.Errorf("total = %v, want %v", o.TotalPrice, tt.total)
+			}
+		})
+	}
+}
+
+func TestApplyTaxesWithoutCalculator(t *testing.T) {
+	s := NewService(nil, nil, nil, nil, nil, "")
+	o := &Order{
+		Products:  []OrderedProduct{{ID: "a", Price: 10, Quantity: 2}},
+		Discounts: []Discount{{ProductID: "a", Code: "F5", Amount: 5}},
+	}
+	if err := s.applyTaxes(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	if o.TaxMode != TaxExclusive || len(o.TaxLines) != 0 || o.TotalPrice != 15 {
+		t.Errorf("got mode %s, tax lines %+v, total %v, want exclusive, none, 15", o.TaxMode, o.TaxLines, o.TotalPrice)
+	}
+}
@@ -11,4 +11,183 @@ CREATE TABLE IF NOT EXISTS order_products (
     product_id VARCHAR(27) NOT NULL,
     quantity INT NOT NULL,
     PRIMARY KEY (order_id, product_id)
-);
\ No newline at end of file
+);
+
+-- The products that ship for each bundle line, quantities already
+-- multiplied by the number of bundles ordered.
+CREATE TABLE IF NOT EXISTS order_product_components (
+    order_id VARCHAR(27) NOT NULL,
+    bundle_id VARCHAR(27) NOT NULL,
+    product_id VARCHAR(27) NOT NULL,
+    quantity INT NOT NULL,
+    PRIMARY KEY (order_id, bundle_id, product_id),
+    FOREIGN KEY (order_id, bundle_id) REFERENCES order_products(order_id, product_id) ON DELETE CASCADE
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'pending';
+
+-- Every status an order went through, from_status is empty for the status
+-- it was placed with.
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(27) REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    actor VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id ON order_status_history(order_id);
+
+-- What each line cost when the order was placed.
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS price MONEY NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id VARCHAR(27) NOT NULL,
+    order_id VARCHAR(27) REFERENCES orders(id) ON DELETE CASCADE,
+    amount MONEY NOT NULL,
+    reason TEXT NOT NULL,
+    actor VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS order_refund_lines (
+    refund_id VARCHAR(27) REFERENCES order_refunds(id) ON DELETE CASCADE,
+    product_id VARCHAR(27) NOT NULL,
+    quantity INT NOT NULL,
+    amount MONEY NOT NULL,
+    PRIMARY KEY (refund_id, product_id)
+);
+
+-- Product details as they were when the order was placed. Lines of older
+-- orders have them empty.
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS name TEXT NOT NULL DEFAULT '';
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE order_products ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Stock of the products whose stock is tracked, products without a row
+-- are always available.
+CREATE TABLE IF NOT EXISTS stock (
+    product_id VARCHAR(27) NOT NULL,
+    available INT NOT NULL CHECK (available >= 0),
+    PRIMARY KEY (product_id)
+);
+
+-- What orders took out of stock and have not put back yet.
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    order_id VARCHAR(27) NOT NULL,
+    product_id VARCHAR(27) NOT NULL,
+    quantity INT NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+
+-- Progress of placing each order, state holds the order and its steps.
+CREATE TABLE IF NOT EXISTS order_sagas (
+    id VARCHAR(27) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    state JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS order_sagas_status_updated_at ON order_sagas(status, updated_at);
+
+-- Events waiting to be published, written together with the change they
+-- announce. Published events are kept, published_at tells them apart.
+CREATE TABLE IF NOT EXISTS order_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(27) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS order_outbox_unpublished ON order_outbox(order_id, id) WHERE published_at IS NULL;
+
+-- A cart belongs to an account or, for guests, to a token.
+CREATE TABLE IF NOT EXISTS carts (
+    id VARCHAR(27) NOT NULL,
+    account_id VARCHAR(27) UNIQUE,
+    token VARCHAR(64) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id),
+    CHECK (account_id IS NOT NULL OR token IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS carts_expires_at ON carts(expires_at);
+
+-- Lines keep the order they were added in.
+CREATE TABLE IF NOT EXISTS cart_lines (
+    cart_id VARCHAR(27) REFERENCES carts(id) ON DELETE CASCADE,
+    product_id VARCHAR(27) NOT NULL,
+    quantity INT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (cart_id, product_id)
+);
+
+-- Coupon codes. Zero limits mean unlimited, NULL bounds open ended.
+CREATE TABLE IF NOT EXISTS promotions (
+    code VARCHAR(64) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    product_id VARCHAR(27) NOT NULL DEFAULT '',
+    buy_quantity INT NOT NULL DEFAULT 0,
+    get_quantity INT NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    max_uses INT NOT NULL DEFAULT 0,
+    max_uses_per_account INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (code)
+);
+
+-- The orders each coupon was used on, cancelled orders give their use
+-- back.
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    code VARCHAR(64) REFERENCES promotions(code),
+    order_id VARCHAR(27) REFERENCES orders(id) ON DELETE CASCADE,
+    account_id VARCHAR(27) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (code, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS promotion_redemptions_account_id ON promotion_redemptions(code, account_id);
+
+-- What each coupon took off each line of an order.
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(27) REFERENCES orders(id) ON DELETE CASCADE,
+    product_id VARCHAR(27) NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    amount MONEY NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_discounts_order_id ON order_discounts(order_id);
+
+-- Where each order ships and whether its prices included tax. Orders
+-- placed before taxes have no address and no tax lines.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS country VARCHAR(2) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS region VARCHAR(8) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_mode VARCHAR(16) NOT NULL DEFAULT 'exclusive';
+
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(27) REFERENCES orders(id) ON DELETE CASCADE,
+    product_id VARCHAR(27) NOT NULL,
+    tax_class VARCHAR(32) NOT NULL,
+    jurisdiction VARCHAR(16) NOT NULL,
+    rate DOUBLE PRECISION NOT NULL,
+    taxable MONEY NOT NULL,
+    amount MONEY NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_tax_lines_order_id ON order_tax_lines(order_id);